@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// amplificationRiskFactor is the response/request ratio above which a
+// query is reported as an amplification risk.
+const amplificationRiskFactor = 1000
+
+// amplificationTypes are the query types measured for every name.
+var amplificationTypes = []dnsmessage.Type{
+	dnsmessage.TypeA, dnsmessage.TypeAAAA, dnsmessage.TypeMX, dnsmessage.TypeTXT,
+	dnsmessage.TypeNS, dnsmessage.TypeSOA, typeDNSKEY, dnsmessage.TypeALL,
+}
+
+// AmplificationMeasurement is the size of one query and its response.
+type AmplificationMeasurement struct {
+	Name          string
+	Type          dnsmessage.Type
+	RequestBytes  int
+	ResponseBytes int
+	Factor        float64
+}
+
+// measureDNSResponseSize sends a single EDNS0 query over UDP to ns and
+// returns the size in bytes of the query and of the response.
+func measureDNSResponseSize(domain, ns string, qtype dnsmessage.Type) (requestBytes, responseBytes int, err error) {
+	name, err := dnsmessage.NewName(dnsName(domain))
+	if err != nil {
+		return 0, 0, err
+	}
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: uint16(rand.Intn(1 << 16)), OpCode: OpCodeQuery})
+	builder.EnableCompression()
+	builder.StartQuestions()
+	builder.Question(dnsmessage.Question{Name: name, Type: qtype, Class: dnsClass})
+	builder.StartAdditionals()
+	var opt dnsmessage.ResourceHeader
+	// Advertise a large buffer so the server is free to answer in full
+	opt.SetEDNS0(4096, dnsmessage.RCodeSuccess, false)
+	builder.OPTResource(opt, dnsmessage.OPTResource{})
+	query, err := builder.Finish()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dnsQueryTimeout)
+	defer cancel()
+	conn, err := newDialer().DialContext(ctx, "udp", net.JoinHostPort(ns, "53"))
+	if err != nil {
+		return 0, 0, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(dnsQueryTimeout))
+
+	if _, err := conn.Write(query); err != nil {
+		return 0, 0, err
+	}
+	buf := make([]byte, 65535)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return len(query), 0, err
+	}
+	return len(query), n, nil
+}
+
+// measureAmplification measures every name/type pair against the first
+// nameserver of domain and prints the worst amplification factors.
+func measureAmplification(domain string, names []string) []AmplificationMeasurement {
+	nameServers, err := resolver.LookupNS(context.Background(), domain)
+	if err != nil || len(nameServers) == 0 {
+		log.Printf("Failed to get NS records for amplification measurement of %s: %v\n", domain, err)
+		return nil
+	}
+	ns := nameServers[0].Host
+
+	var measurements []AmplificationMeasurement
+	for _, name := range append([]string{domain}, names...) {
+		for _, qtype := range amplificationTypes {
+			req, res, err := measureDNSResponseSize(name, ns, qtype)
+			if err != nil || req == 0 {
+				continue
+			}
+			measurements = append(measurements, AmplificationMeasurement{
+				Name:          name,
+				Type:          qtype,
+				RequestBytes:  req,
+				ResponseBytes: res,
+				Factor:        float64(res) / float64(req),
+			})
+		}
+	}
+	sort.Slice(measurements, func(i, j int) bool {
+		return measurements[i].Factor > measurements[j].Factor
+	})
+
+	for i, m := range measurements {
+		if i == 20 {
+			break
+		}
+		flag := ""
+		if m.Factor > amplificationRiskFactor {
+			flag = " [AMPLIFICATION-RISK]"
+		}
+		fmt.Fprintf(status, " - %-40s %-8s %5d -> %5d bytes (%.1fx)%s\n", m.Name, typeName(m.Type), m.RequestBytes, m.ResponseBytes, m.Factor, flag)
+	}
+	if verbose {
+		fmt.Fprint(status, generateAmplificationReport(measurements))
+	}
+	return measurements
+}
+
+// generateAmplificationReport turns the measurements into hardening advice
+// for the zone's operators, pointing at the worst offenders it saw.
+func generateAmplificationReport(measurements []AmplificationMeasurement) string {
+	if len(measurements) == 0 {
+		return ""
+	}
+	var worst, worstAny AmplificationMeasurement
+	var risky int
+	for _, m := range measurements {
+		if m.Factor > worst.Factor {
+			worst = m
+		}
+		if m.Type == dnsmessage.TypeALL && m.Factor > worstAny.Factor {
+			worstAny = m
+		}
+		if m.Factor > amplificationRiskFactor {
+			risky++
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "\nAmplification recommendations:\n")
+	fmt.Fprintf(&b, "   Worst factor %.1fx for %s %s, %d of %d queries above %dx.\n",
+		worst.Factor, typeName(worst.Type), worst.Name, risky, len(measurements), amplificationRiskFactor)
+	if worstAny.Factor > 1 {
+		fmt.Fprintf(&b, " - Disable or minimize ANY answers (RFC 8482), ANY %s amplifies %.1fx.\n", worstAny.Name, worstAny.Factor)
+	} else {
+		fmt.Fprintf(&b, " - ANY answers are already minimal, keep ANY disabled (RFC 8482).\n")
+	}
+	fmt.Fprintf(&b, " - Enable Response Rate Limiting (RRL) on every authoritative server so repeated large answers to one source are dropped or truncated.\n")
+	fmt.Fprintf(&b, " - Restrict AXFR and IXFR to the IP addresses of authorized secondaries, ideally with TSIG.\n")
+	fmt.Fprintf(&b, " - Require source address validation (BCP 38) at the network edge so spoofed queries never reach the servers, and prefer TCP or DNS cookies (RFC 7873) for large responses.\n")
+	return b.String()
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// ansibleGroups are the inventory groups, in the order they are written.
+var ansibleGroups = []string{"web", "api", "admin", "mail", "vpn"}
+
+// serviceLabels maps the first label of a name to its service type when the
+// HTTP response gives nothing more specific.
+var serviceLabels = map[string]string{
+	"mail": "mail", "smtp": "mail", "mx": "mail", "imap": "mail", "pop": "mail", "webmail": "mail", "autodiscover": "mail",
+	"vpn": "vpn", "sslvpn": "vpn", "remote": "vpn", "gp": "vpn",
+	"admin": "admin", "adminpanel": "admin", "panel": "admin", "manage": "admin", "console": "admin", "cpanel": "admin",
+	"api": "api", "graphql": "api", "rest": "api",
+}
+
+// classifyServices requests / from every name in records over HTTPS and
+// sets the service type and TLS version on its records. Names that do not
+// answer keep only a type derived from their first label, if any.
+func classifyServices(records []DiscoveryRecord) {
+	type result struct{ service, version string }
+	results := make(map[string]result)
+	for i := range records {
+		name := records[i].Name
+		r, ok := results[name]
+		if !ok {
+			r.service, r.version = probeService(name)
+			results[name] = r
+		}
+		records[i].Service, records[i].TLSVersion = r.service, r.version
+	}
+}
+
+func probeService(host string) (string, string) {
+	label, _, _ := strings.Cut(host, ".")
+	service := serviceLabels[strings.ToLower(label)]
+
+	resp, err := probeClient.Get("https://" + host + "/")
+	if err != nil {
+		return service, ""
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	version := ""
+	if resp.TLS != nil {
+		version = strings.Replace(tls.VersionName(resp.TLS.Version), "TLS ", "TLSv", 1)
+	}
+
+	page := strings.ToLower(string(body))
+	for _, b := range sslVPNBanners {
+		if strings.Contains(page, b.marker) {
+			return "vpn", version
+		}
+	}
+	switch {
+	case service != "":
+	case strings.Contains(resp.Header.Get("Content-Type"), "json"):
+		service = "api"
+	case strings.Contains(page, "type=\"password\"") && (strings.Contains(page, "admin") || strings.Contains(page, "dashboard")):
+		service = "admin"
+	default:
+		service = "web"
+	}
+	return service, version
+}
+
+// writeAnsibleInventory writes results to path as an Ansible INI inventory
+// with one group per service type. Each host gets its first address as
+// ansible_host and, when it was probed over HTTPS, its tls_version.
+func writeAnsibleInventory(results []DiscoveryRecord, path string) error {
+	type host struct{ addr, service, version string }
+	var order []string
+	hosts := make(map[string]*host)
+	for _, record := range results {
+		h, ok := hosts[record.Name]
+		if !ok {
+			h = &host{service: record.Service, version: record.TLSVersion}
+			hosts[record.Name] = h
+			order = append(order, record.Name)
+		}
+		// Prefer an IPv4 address, it is what most playbooks connect to
+		if record.Type == dnsmessage.TypeA && (h.addr == "" || strings.Contains(h.addr, ":")) {
+			h.addr = record.Data
+		} else if record.Type == dnsmessage.TypeAAAA && h.addr == "" {
+			h.addr = record.Data
+		}
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+	line := func(name string) {
+		h := hosts[name]
+		fmt.Fprint(w, name)
+		if h.addr != "" {
+			fmt.Fprintf(w, " ansible_host=%s", h.addr)
+		}
+		if h.version != "" {
+			fmt.Fprintf(w, " tls_version=%s", h.version)
+		}
+		fmt.Fprintln(w)
+	}
+	// Ungrouped hosts have to come before the first group header
+	for _, name := range order {
+		if hosts[name].service == "" {
+			line(name)
+		}
+	}
+	for _, group := range ansibleGroups {
+		fmt.Fprintf(w, "\n[%s]\n", group)
+		for _, name := range order {
+			if hosts[name].service == group {
+				line(name)
+			}
+		}
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
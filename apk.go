@@ -0,0 +1,53 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// apkSkipExtensions are media files that never contain hostnames.
+var apkSkipExtensions = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".webp": true,
+	".mp3": true, ".mp4": true, ".ogg": true, ".ttf": true, ".otf": true,
+}
+
+// extractDomainsFromAPK scans every file of an APK or IPA, both are zip
+// archives, for subdomains of targetDomain. Compiled code such as
+// classes.dex keeps its string constants as plain text, so binary files
+// are searched as well, only media is skipped.
+func extractDomainsFromAPK(apkPath, targetDomain string) ([]string, error) {
+	archive, err := zip.OpenReader(apkPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", apkPath, err)
+	}
+	defer archive.Close()
+
+	pattern := subdomainPattern(targetDomain)
+	seen := make(map[string]bool)
+	var result []string
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() || apkSkipExtensions[strings.ToLower(path.Ext(file.Name))] {
+			continue
+		}
+		r, err := file.Open()
+		if err != nil {
+			continue
+		}
+		data, err := io.ReadAll(io.LimitReader(r, 64<<20))
+		r.Close()
+		if err != nil {
+			continue
+		}
+		for _, name := range matchSubdomains(pattern, string(data), targetDomain) {
+			if !seen[name] {
+				seen[name] = true
+				result = append(result, name)
+				fmt.Fprintf(status, " - [APK] %s (%s)\n", name, file.Name)
+			}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	resultsArchive = "results.tar.gz"
+	artifactName   = "sub_sniaX-results"
+	artifactRPC    = "/twirp/github.actions.results.api.v1.ArtifactService/"
+)
+
+// writeResultsArchive packs files into a gzipped tarball at path.
+func writeResultsArchive(path string, files []string) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+	for _, file := range files {
+		if err := addToTar(tw, file); err != nil {
+			return fmt.Errorf("failed to archive %s: %w", file, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+func addToTar(tw *tar.Writer, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = filepath.Base(file)
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// uploadArtifact uploads path as a GitHub Actions workflow artifact using
+// the v4 artifact service. The runner only exposes ACTIONS_RUNTIME_TOKEN
+// and ACTIONS_RESULTS_URL to actions, so run steps need them exported
+// (e.g. with crazy-max/ghaction-github-runtime); GITHUB_TOKEN cannot
+// authorize artifact uploads.
+func uploadArtifact(name, path string) error {
+	token := os.Getenv("ACTIONS_RUNTIME_TOKEN")
+	resultsURL := strings.TrimSuffix(os.Getenv("ACTIONS_RESULTS_URL"), "/")
+	if token == "" || resultsURL == "" {
+		return errors.New("ACTIONS_RUNTIME_TOKEN and ACTIONS_RESULTS_URL must be set, are we running in GitHub Actions?")
+	}
+	runID, jobID, err := backendIDs(token)
+	if err != nil {
+		return err
+	}
+
+	// Artifacts are served back as zip files, so the blob has to be one
+	blob, err := zipFile(path)
+	if err != nil {
+		return err
+	}
+
+	var created struct {
+		OK        bool   `json:"ok"`
+		UploadURL string `json:"signed_upload_url"`
+	}
+	err = artifactCall(resultsURL, token, "CreateArtifact", map[string]any{
+		"workflow_run_backend_id":     runID,
+		"workflow_job_run_backend_id": jobID,
+		"name":                        name,
+		"version":                     4,
+	}, &created)
+	if err != nil {
+		return err
+	}
+	if !created.OK || created.UploadURL == "" {
+		return errors.New("artifact service refused to create the artifact")
+	}
+
+	req, err := http.NewRequest(http.MethodPut, created.UploadURL, bytes.NewReader(blob))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("x-ms-blob-type", "BlockBlob")
+	req.Header.Set("Content-Type", "application/zip")
+	resp, err := apiClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("artifact upload failed: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("artifact upload returned %s", resp.Status)
+	}
+
+	sum := sha256.Sum256(blob)
+	var finalized struct {
+		OK bool `json:"ok"`
+	}
+	err = artifactCall(resultsURL, token, "FinalizeArtifact", map[string]any{
+		"workflow_run_backend_id":     runID,
+		"workflow_job_run_backend_id": jobID,
+		"name":                        name,
+		"size":                        strconv.Itoa(len(blob)),
+		"hash":                        "sha256:" + hex.EncodeToString(sum[:]),
+	}, &finalized)
+	if err != nil {
+		return err
+	}
+	if !finalized.OK {
+		return errors.New("artifact service refused to finalize the artifact")
+	}
+	return nil
+}
+
+func artifactCall(resultsURL, token, method string, body, out any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, resultsURL+artifactRPC+method, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := apiClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s failed: %w", method, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("%s returned %s: %s", method, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// backendIDs extracts the workflow run and job IDs from the runtime token,
+// whose scp claim contains "Actions.Results:<run>:<job>".
+func backendIDs(token string) (string, string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", "", errors.New("ACTIONS_RUNTIME_TOKEN is not a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", "", fmt.Errorf("failed to decode runtime token: %w", err)
+	}
+	var claims struct {
+		Scope string `json:"scp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return "", "", fmt.Errorf("failed to decode runtime token: %w", err)
+	}
+	for _, scope := range strings.Fields(claims.Scope) {
+		ids := strings.Split(scope, ":")
+		if len(ids) == 3 && ids[0] == "Actions.Results" {
+			return ids[1], ids[2], nil
+		}
+	}
+	return "", "", errors.New("runtime token has no Actions.Results scope")
+}
+
+func zipFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create(filepath.Base(path))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
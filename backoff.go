@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultBackoff is the pause after a 429 without a usable Retry-After.
+	defaultBackoff = 10 * time.Second
+	// maxBackoff caps the pause so one host cannot stall a pass for long.
+	// Longer Retry-After values are still honored, without a retry.
+	maxBackoff = 2 * time.Minute
+)
+
+// HostBackoffManager tracks, per host, when it may be probed again after
+// answering 429 Too Many Requests.
+type HostBackoffManager struct {
+	mu    sync.Mutex
+	until map[string]time.Time
+}
+
+func newHostBackoffManager() *HostBackoffManager {
+	return &HostBackoffManager{until: make(map[string]time.Time)}
+}
+
+// probeBackoff is shared by every request sent through probeClient.
+var probeBackoff = newHostBackoffManager()
+
+// Wait blocks until host may be probed again or ctx is done. When the
+// pause outlasts the deadline of ctx it fails right away instead.
+func (m *HostBackoffManager) Wait(ctx context.Context, host string) error {
+	m.mu.Lock()
+	until := m.until[host]
+	m.mu.Unlock()
+	delay := time.Until(until)
+	if delay <= 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(until) {
+		return fmt.Errorf("%s is rate limited for another %s", host, delay.Round(time.Second))
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Backoff keeps host from being probed for d, extending any pause already
+// in place.
+func (m *HostBackoffManager) Backoff(host string, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if until := time.Now().Add(d); until.After(m.until[host]) {
+		m.until[host] = until
+	}
+}
+
+// retryAfter returns the pause a 429 response asks for, either in seconds
+// or as an HTTP date, and defaultBackoff when it names none.
+func retryAfter(resp *http.Response) time.Duration {
+	value := resp.Header.Get("Retry-After")
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0)
+	}
+	return defaultBackoff
+}
+
+// backoffTransport holds requests to a host while it is backing off. A
+// 429 starts a pause for its Retry-After and, when the pause is within
+// maxBackoff and the request can be replayed, the request is sent once
+// more after it.
+type backoffTransport struct {
+	base  http.RoundTripper
+	hosts *HostBackoffManager
+}
+
+func (t backoffTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Hostname()
+	if err := t.hosts.Wait(req.Context(), host); err != nil {
+		return nil, err
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusTooManyRequests {
+		return resp, err
+	}
+
+	pause := retryAfter(resp)
+	t.hosts.Backoff(host, pause)
+	fmt.Fprintf(status, " - [RATE-LIMITED] %s, pausing it for %s\n", host, pause.Round(time.Second))
+	if pause > maxBackoff || (req.Body != nil && req.GetBody == nil) {
+		return resp, nil
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	if err := t.hosts.Wait(req.Context(), host); err != nil {
+		return nil, err
+	}
+	return t.base.RoundTrip(retry)
+}
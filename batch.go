@@ -0,0 +1,160 @@
+package main
+
+import (
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	// batchSize keeps the queries of one batch within the 16-bit ID space.
+	batchSize = 1000
+	// batchWindow is how many queries are in flight at once. Writing far
+	// more than that in one burst overruns socket buffers and loses
+	// packets even on loopback.
+	batchWindow = 100
+	// batchRetransmit is how long an unanswered query waits before it is
+	// sent again.
+	batchRetransmit = 500 * time.Millisecond
+)
+
+// batchResolve resolves names over a single UDP socket to the nameserver
+// at server (host or host:port). A and AAAA queries are pipelined, up to
+// batchWindow at a time, and the answers matched back by message ID, which
+// is far faster than one lookup per name for hundreds of candidates.
+// Resolution gives up once no answer has arrived for timeout. Names that
+// do not resolve are absent from the result.
+func batchResolve(names []string, server string, timeout time.Duration) map[string][]net.IP {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	result := make(map[string][]net.IP)
+	for start := 0; start < len(names); start += batchSize {
+		resolveBatch(names[start:min(start+batchSize, len(names))], server, timeout, result)
+	}
+	return result
+}
+
+type batchQuery struct {
+	name   string
+	packed []byte
+	sent   bool
+	done   bool
+}
+
+func resolveBatch(names []string, server string, timeout time.Duration, result map[string][]net.IP) {
+	conn, err := newDialer().Dial("udp", server)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	queries := make(map[uint16]*batchQuery)
+	var order []*batchQuery
+	base := uint16(rand.Intn(1 << 16))
+	for i, name := range names {
+		qname, err := dnsmessage.NewName(dnsName(name))
+		if err != nil {
+			continue
+		}
+		for j, qtype := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+			id := base + uint16(2*i+j)
+			msg := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: id, RecursionDesired: true, OpCode: OpCodeQuery},
+				Questions: []dnsmessage.Question{{Name: qname, Type: qtype, Class: dnsClass}},
+			}
+			packed, err := msg.Pack()
+			if err != nil {
+				continue
+			}
+			q := &batchQuery{name: name, packed: packed}
+			queries[id] = q
+			order = append(order, q)
+		}
+	}
+
+	var next, inflight, pending int
+	pending = len(order)
+	fill := func() {
+		for inflight < batchWindow && next < len(order) {
+			conn.Write(order[next].packed)
+			order[next].sent = true
+			next++
+			inflight++
+		}
+	}
+	fill()
+
+	lastAnswer := time.Now()
+	buf := make([]byte, 65535)
+	for pending > 0 {
+		conn.SetReadDeadline(time.Now().Add(batchRetransmit))
+		n, err := conn.Read(buf)
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				if time.Since(lastAnswer) >= timeout {
+					return
+				}
+				for _, q := range order[:next] {
+					if !q.done {
+						conn.Write(q.packed)
+					}
+				}
+			}
+			continue
+		}
+
+		var resp dnsmessage.Message
+		if err := resp.Unpack(buf[:n]); err != nil {
+			continue
+		}
+		q, ok := queries[resp.Header.ID]
+		if !ok || !q.sent || q.done || len(resp.Questions) == 0 || !strings.EqualFold(resp.Questions[0].Name.String(), dnsName(q.name)) {
+			continue
+		}
+		q.done = true
+		pending--
+		inflight--
+		lastAnswer = time.Now()
+		for _, rr := range resp.Answers {
+			switch body := rr.Body.(type) {
+			case *dnsmessage.AResource:
+				result[q.name] = append(result[q.name], net.IP(body.A[:]))
+			case *dnsmessage.AAAAResource:
+				result[q.name] = append(result[q.name], net.IP(body.AAAA[:]))
+			}
+		}
+		fill()
+	}
+}
+
+// resolvingNames returns the names that have an address. It uses
+// batchResolve against the first -resolver server or the system
+// nameserver. DoH resolvers cannot take raw UDP, and under -rps a batch
+// would send all of its queries for one wait on the limiter, so then the
+// names are asked one at a time.
+func resolvingNames(names []string) []string {
+	var result []string
+	if limiter != nil || (resolver.Dial != nil && len(resolverServers) == 0) {
+		for _, name := range names {
+			if len(lookupIPs(name)) > 0 {
+				result = append(result, name)
+			}
+		}
+		return result
+	}
+	server := systemNameserver()
+	if len(resolverServers) > 0 {
+		server = resolverServers[0]
+	}
+	resolved := batchResolve(names, server, dnsQueryTimeout)
+	for _, name := range names {
+		if len(resolved[name]) > 0 {
+			result = append(result, name)
+		}
+	}
+	return result
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const ripeStatPrefixOverview = "https://stat.ripe.net/data/prefix-overview/data.json?resource="
+
+type ripeStatPrefixResponse struct {
+	Data struct {
+		Announced bool `json:"announced"`
+		ASNs      []struct {
+			ASN    int    `json:"asn"`
+			Holder string `json:"holder"`
+		} `json:"asns"`
+	} `json:"data"`
+}
+
+// validateBGPRoute asks RIPEstat which AS originates the best route covering
+// ip and reports whether it matches expectedASN.
+func validateBGPRoute(ip net.IP, expectedASN int) (bool, error) {
+	origins, err := bgpOrigins(ip)
+	if err != nil {
+		return false, err
+	}
+	return slices.Contains(origins, expectedASN), nil
+}
+
+func bgpOrigins(ip net.IP) ([]int, error) {
+	resp, err := apiClient.Get(ripeStatPrefixOverview + url.QueryEscape(ip.String()))
+	if err != nil {
+		return nil, fmt.Errorf("RIPEstat request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("RIPEstat returned %s", resp.Status)
+	}
+
+	var overview ripeStatPrefixResponse
+	if err := json.NewDecoder(resp.Body).Decode(&overview); err != nil {
+		return nil, fmt.Errorf("failed to decode RIPEstat response: %w", err)
+	}
+	if !overview.Data.Announced {
+		return nil, fmt.Errorf("%s is not covered by any announced prefix", ip)
+	}
+	var origins []int
+	for _, asn := range overview.Data.ASNs {
+		origins = append(origins, asn.ASN)
+	}
+	return origins, nil
+}
+
+// checkBGPRoutes resolves every subdomain and flags addresses whose route
+// is originated by an AS other than expectedASN.
+func checkBGPRoutes(subdomains []string, expectedASN int) {
+	checked := make(map[string]bool)
+	for _, subdomain := range subdomains {
+		for _, ip := range lookupIPs(subdomain) {
+			if checked[ip.String()] {
+				continue
+			}
+			checked[ip.String()] = true
+
+			origins, err := bgpOrigins(ip)
+			if err != nil {
+				log.Printf("Failed to validate BGP route for %s: %v\n", ip, err)
+				continue
+			}
+			if !slices.Contains(origins, expectedASN) {
+				var seen []string
+				for _, asn := range origins {
+					seen = append(seen, "AS"+strconv.Itoa(asn))
+				}
+				fmt.Fprintf(status, " - [BGP-MISMATCH] %s (%s) originated by %s, expected AS%d\n", subdomain, ip, strings.Join(seen, ", "), expectedASN)
+			}
+		}
+	}
+}
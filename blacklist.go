@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Blacklist feed formats.
+const (
+	feedHosts = iota // hosts file lines, "127.0.0.1 name"
+	feedIPs          // one address per line
+	feedURLs         // one URL per line
+)
+
+// BlacklistSource is a public malware or phishing feed. Entries holds the
+// listed hostnames or addresses once the feed has been downloaded.
+type BlacklistSource struct {
+	Name    string
+	URL     string
+	Format  int
+	Entries map[string]bool
+}
+
+var blacklistFeeds = []BlacklistSource{
+	{Name: "URLhaus", URL: "https://urlhaus.abuse.ch/downloads/hostfile/", Format: feedHosts},
+	{Name: "Emerging Threats", URL: "https://rules.emergingthreats.net/blockrules/compromised-ips.txt", Format: feedIPs},
+	{Name: "OpenPhish", URL: "https://openphish.com/feed.txt", Format: feedURLs},
+}
+
+// loadBlacklists downloads every feed once per run. Feeds that cannot be
+// fetched are left out with a warning.
+var loadBlacklists = sync.OnceValue(func() []BlacklistSource {
+	var lists []BlacklistSource
+	for _, feed := range blacklistFeeds {
+		entries, err := fetchBlacklist(feed)
+		if err != nil {
+			log.Printf("Failed to download the %s blacklist: %v\n", feed.Name, err)
+			continue
+		}
+		feed.Entries = entries
+		lists = append(lists, feed)
+	}
+	return lists
+})
+
+func fetchBlacklist(feed BlacklistSource) (map[string]bool, error) {
+	resp, err := apiClient.Get(feed.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", feed.URL, resp.Status)
+	}
+
+	entries := make(map[string]bool)
+	scanner := bufio.NewScanner(io.LimitReader(resp.Body, 64<<20))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		switch feed.Format {
+		case feedHosts:
+			if fields := strings.Fields(line); len(fields) >= 2 {
+				entries[strings.ToLower(fields[1])] = true
+			}
+		case feedIPs:
+			entries[line] = true
+		case feedURLs:
+			if u, err := url.Parse(line); err == nil && u.Hostname() != "" {
+				entries[strings.ToLower(u.Hostname())] = true
+			}
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// checkBlacklists returns the names of the lists that contain subdomain,
+// or for address feeds, one of the addresses it resolves to.
+func checkBlacklists(subdomain string, lists []BlacklistSource) []string {
+	var ips []net.IP
+	var resolved bool
+	var matches []string
+	for _, list := range lists {
+		if list.Format != feedIPs {
+			if list.Entries[subdomain] {
+				matches = append(matches, list.Name)
+			}
+			continue
+		}
+		if !resolved {
+			ips, resolved = lookupIPs(subdomain), true
+		}
+		for _, ip := range ips {
+			if list.Entries[ip.String()] {
+				matches = append(matches, list.Name)
+				break
+			}
+		}
+	}
+	return matches
+}
+
+// checkAllBlacklists flags every host listed in a blacklist feed.
+func checkAllBlacklists(hosts []string) {
+	lists := loadBlacklists()
+	for _, host := range hosts {
+		for _, name := range checkBlacklists(host, lists) {
+			fmt.Fprintf(status, " - [BLACKLISTED: %s] %s\n", name, host)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"sort"
+	"strings"
+)
+
+// cdnRanges lists published edge ranges of the common CDNs. Akamai and
+// CloudFront announce far more than this, the blocks below cover most of
+// the addresses seen in practice.
+var cdnRanges = map[string][]netip.Prefix{
+	"Cloudflare": prefixes(
+		"173.245.48.0/20", "103.21.244.0/22", "103.22.200.0/22", "103.31.4.0/22",
+		"141.101.64.0/18", "108.162.192.0/18", "190.93.240.0/20", "188.114.96.0/20",
+		"197.234.240.0/22", "198.41.128.0/17", "162.158.0.0/15", "104.16.0.0/13",
+		"104.24.0.0/14", "172.64.0.0/13", "131.0.72.0/22",
+		"2400:cb00::/32", "2606:4700::/32", "2803:f800::/32", "2405:b500::/32",
+		"2405:8100::/32", "2a06:98c0::/29", "2c0f:f248::/32",
+	),
+	"Fastly": prefixes(
+		"23.235.32.0/20", "43.249.72.0/22", "103.244.50.0/24", "103.245.222.0/23",
+		"103.245.224.0/24", "104.156.80.0/20", "140.248.64.0/18", "140.248.128.0/17",
+		"146.75.0.0/17", "151.101.0.0/16", "157.52.64.0/18", "167.82.0.0/17",
+		"172.111.64.0/18", "185.31.16.0/22", "199.27.72.0/21", "199.232.0.0/16",
+		"2a04:4e40::/32", "2a04:4e42::/32",
+	),
+	"CloudFront": prefixes(
+		"13.32.0.0/15", "13.35.0.0/16", "18.64.0.0/14", "52.84.0.0/15",
+		"54.182.0.0/16", "54.192.0.0/16", "54.230.0.0/16", "54.239.128.0/18",
+		"99.84.0.0/16", "143.204.0.0/16", "204.246.164.0/22", "205.251.192.0/19",
+		"2600:9000::/28",
+	),
+	"Akamai": prefixes(
+		"2.16.0.0/13", "23.32.0.0/11", "23.192.0.0/11", "72.246.0.0/15",
+		"88.221.0.0/16", "95.100.0.0/15", "96.6.0.0/15", "96.16.0.0/15",
+		"104.64.0.0/10", "184.24.0.0/13", "184.50.0.0/15", "184.84.0.0/14",
+	),
+}
+
+// cdnSuffixes maps CNAME targets to the CDN serving them.
+var cdnSuffixes = map[string]string{
+	".cdn.cloudflare.net": "Cloudflare",
+	".akamai.net":         "Akamai",
+	".akamaiedge.net":     "Akamai",
+	".akamaihd.net":       "Akamai",
+	".edgekey.net":        "Akamai",
+	".edgesuite.net":      "Akamai",
+	".fastly.net":         "Fastly",
+	".fastlylb.net":       "Fastly",
+	".cloudfront.net":     "CloudFront",
+	".azureedge.net":      "Azure CDN",
+	".azurefd.net":        "Azure Front Door",
+	".incapdns.net":       "Imperva",
+	".stackpathdns.com":   "StackPath",
+	".edgecastcdn.net":    "Edgio",
+	".b-cdn.net":          "BunnyCDN",
+	".kxcdn.com":          "KeyCDN",
+}
+
+func prefixes(cidrs ...string) []netip.Prefix {
+	result := make([]netip.Prefix, len(cidrs))
+	for i, cidr := range cidrs {
+		result[i] = netip.MustParsePrefix(cidr)
+	}
+	return result
+}
+
+// detectCDN names the CDN serving subdomain, judged first by its canonical
+// name and then by its addresses. It returns "" when no CDN matches.
+func detectCDN(subdomain string, ips []net.IP, cname string) string {
+	for _, name := range []string{cname, subdomain} {
+		name = "." + strings.ToLower(strings.TrimSuffix(name, "."))
+		for suffix, provider := range cdnSuffixes {
+			if strings.HasSuffix(name, suffix) {
+				return provider
+			}
+		}
+	}
+	for _, ip := range ips {
+		addr, ok := netip.AddrFromSlice(ip)
+		if !ok {
+			continue
+		}
+		addr = addr.Unmap()
+		for provider, ranges := range cdnRanges {
+			for _, prefix := range ranges {
+				if prefix.Contains(addr) {
+					return provider
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// checkCDN prints the CDN in front of every host that has one and returns
+// the providers keyed by host.
+func checkCDN(hosts []string) map[string]string {
+	providers := make(map[string]string)
+	for _, host := range hosts {
+		if provider := cdnProvider(host); provider != "" {
+			providers[host] = provider
+		}
+	}
+
+	sorted := make([]string, 0, len(providers))
+	for host := range providers {
+		sorted = append(sorted, host)
+	}
+	sort.Strings(sorted)
+	for _, host := range sorted {
+		fmt.Fprintf(status, " - [CDN: %s] %s\n", providers[host], host)
+	}
+	return providers
+}
+
+// cdnProvider resolves host and names the CDN serving it, if any.
+func cdnProvider(host string) string {
+	cname, _ := resolver.LookupCNAME(context.Background(), host)
+	return detectCDN(host, lookupIPs(host), cname)
+}
+
+// resultCDN names the CDN serving host for its -json result, judged by the
+// addresses the result already holds when there are any.
+func resultCDN(host string, addrs []string) string {
+	if len(addrs) == 0 {
+		return cdnProvider(host)
+	}
+	ips := make([]net.IP, 0, len(addrs))
+	for _, addr := range addrs {
+		if ip := net.ParseIP(addr); ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+	cname, _ := resolver.LookupCNAME(context.Background(), host)
+	return detectCDN(host, ips, cname)
+}
+
+// excludeCDN marks the hosts served by a CDN and returns the others, so
+// the probes that follow only reach origin infrastructure.
+func excludeCDN(hosts []string) []string {
+	var origins []string
+	for _, host := range hosts {
+		if provider := cdnProvider(host); provider != "" {
+			fmt.Fprintf(status, " - [CDN] %s (%s)\n", host, provider)
+			continue
+		}
+		origins = append(origins, host)
+	}
+	return origins
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net"
+	"testing"
+)
+
+func TestDetectCDN(t *testing.T) {
+	tests := []struct {
+		subdomain string
+		ips       []string
+		cname     string
+		want      string
+	}{
+		{"www.example.com", nil, "www.example.com.cdn.cloudflare.net.", "Cloudflare"},
+		{"static.example.com", nil, "E1234.A.AKAMAIEDGE.NET.", "Akamai"},
+		{"d111111abcdef8.cloudfront.net", nil, "", "CloudFront"},
+		{"www.example.com", []string{"104.16.1.1"}, "", "Cloudflare"},
+		{"www.example.com", []string{"192.0.2.1", "151.101.1.69"}, "", "Fastly"},
+		{"www.example.com", []string{"::ffff:13.32.4.5"}, "", "CloudFront"},
+		{"www.example.com", []string{"2606:4700::6810:84e5"}, "", "Cloudflare"},
+		{"www.example.com", []string{"192.0.2.1"}, "www.example.com.", ""},
+		{"notfastly.net.example.com", nil, "", ""},
+		{"www.example.com", nil, "", ""},
+	}
+	for _, tt := range tests {
+		var ips []net.IP
+		for _, ip := range tt.ips {
+			ips = append(ips, net.ParseIP(ip))
+		}
+		if got := detectCDN(tt.subdomain, ips, tt.cname); got != tt.want {
+			t.Errorf("detectCDN(%q, %v, %q) = %q, want %q", tt.subdomain, tt.ips, tt.cname, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"strings"
+)
+
+// internalSuffixes are name suffixes that only resolve inside a private
+// network.
+var internalSuffixes = []string{".local", ".internal", ".corp", ".lan", ".intranet", ".private", ".home.arpa"}
+
+// ChainFinding is what the certificate chain of one host reveals about the
+// PKI behind it.
+type ChainFinding struct {
+	Host          string
+	Intermediates []string
+	PrivateCA     bool
+	AIAURLs       []string
+	InternalAIA   []string
+}
+
+// retrieveCertChain returns the full chain host serves on port 443, leaf
+// first, without verifying it.
+func retrieveCertChain(host string) ([]*x509.Certificate, error) {
+	conn, err := tls.DialWithDialer(newDialer(), "tcp", net.JoinHostPort(host, "443"), &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("%s presented no certificate", host)
+	}
+	return certs, nil
+}
+
+// analyzeCertChain names the CAs above the leaf, checks whether the chain
+// leads to a publicly trusted root and collects the OCSP and CA issuer
+// URLs of every certificate. A chain the system roots do not accept is
+// taken as a private PKI, and AIA URLs on private addresses or internal
+// names point at infrastructure inside it.
+func analyzeCertChain(host string, chain []*x509.Certificate) ChainFinding {
+	finding := ChainFinding{Host: host}
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+		finding.Intermediates = append(finding.Intermediates, caLabel(cert))
+	}
+	_, err := chain[0].Verify(x509.VerifyOptions{Intermediates: intermediates})
+	var unknown x509.UnknownAuthorityError
+	finding.PrivateCA = errors.As(err, &unknown)
+
+	seen := make(map[string]bool)
+	for _, cert := range chain {
+		for _, raw := range append(append([]string(nil), cert.OCSPServer...), cert.IssuingCertificateURL...) {
+			if seen[raw] {
+				continue
+			}
+			seen[raw] = true
+			finding.AIAURLs = append(finding.AIAURLs, raw)
+			if internalURL(raw) {
+				finding.InternalAIA = append(finding.InternalAIA, raw)
+			}
+		}
+	}
+	return finding
+}
+
+func caLabel(cert *x509.Certificate) string {
+	if org := strings.Join(cert.Subject.Organization, ", "); org != "" {
+		return org + " / " + cert.Subject.CommonName
+	}
+	return cert.Subject.CommonName
+}
+
+// internalURL reports whether raw points at a private address, a single
+// label host or a name under an internal suffix.
+func internalURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return addr.IsPrivate() || addr.IsLoopback() || addr.IsLinkLocalUnicast()
+	}
+	if host != "" && !strings.Contains(host, ".") {
+		return true
+	}
+	for _, suffix := range internalSuffixes {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkCertChains retrieves the chain of every host and prints the private
+// CAs and internal AIA endpoints it reveals.
+func checkCertChains(hosts []string) []ChainFinding {
+	var findings []ChainFinding
+	for _, host := range hosts {
+		chain, err := retrieveCertChain(host)
+		if err != nil {
+			continue
+		}
+		finding := analyzeCertChain(host, chain)
+		findings = append(findings, finding)
+		if finding.PrivateCA {
+			issuer := caLabel(chain[len(chain)-1])
+			if len(chain) == 1 {
+				issuer = issuerLabel(chain[0])
+			}
+			fmt.Fprintf(status, " - [PRIVATE-CA] %s issued by %s\n", host, issuer)
+		}
+		for _, raw := range finding.InternalAIA {
+			fmt.Fprintf(status, " - [INTERNAL-AIA] %s %s\n", host, raw)
+		}
+		if verbose {
+			for _, ca := range finding.Intermediates {
+				fmt.Fprintf(status, "   %s chain: %s\n", host, ca)
+			}
+			for _, raw := range finding.AIAURLs {
+				fmt.Fprintf(status, "   %s AIA: %s\n", host, raw)
+			}
+		}
+	}
+	return findings
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"nhooyr.io/websocket"
+	"nhooyr.io/websocket/wsjson"
+)
+
+const certstreamURL = "wss://certstream.calidog.io/"
+
+// certstreamMessage holds the parts of a Certstream event we care about.
+type certstreamMessage struct {
+	MessageType string `json:"message_type"`
+	Data        struct {
+		LeafCert struct {
+			AllDomains []string `json:"all_domains"`
+		} `json:"leaf_cert"`
+	} `json:"data"`
+}
+
+// monitorCertstream streams certificate issuance events and reports every
+// new SAN that falls under one of the target domains. It runs until ctx
+// is cancelled.
+func monitorCertstream(ctx context.Context, domains []string, output *os.File) {
+	seen := make(map[string]*subdomainSet, len(domains))
+	for _, domain := range domains {
+		seen[domain] = newSubdomainSet(domain)
+	}
+	for {
+		err := readCertstream(ctx, domains, seen, output)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Certstream connection lost: %v, reconnecting in 5s\n", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+func readCertstream(ctx context.Context, domains []string, seen map[string]*subdomainSet, output *os.File) error {
+	conn, _, err := websocket.Dial(ctx, certstreamURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", certstreamURL, err)
+	}
+	defer conn.Close(websocket.StatusNormalClosure, "")
+	// Certificate updates can carry hundreds of SANs
+	conn.SetReadLimit(1 << 20)
+
+	for {
+		var msg certstreamMessage
+		if err := wsjson.Read(ctx, conn, &msg); err != nil {
+			return err
+		}
+		if msg.MessageType != "certificate_update" {
+			continue
+		}
+		for _, name := range msg.Data.LeafCert.AllDomains {
+			name = strings.TrimPrefix(strings.ToLower(name), "*.")
+			for _, domain := range domains {
+				if strings.HasSuffix(name, "."+domain) {
+					writeOutput([]Finding{{Name: name, Source: "certstream"}}, output, seen[domain])
+					break
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// cipherWorkers is the number of handshakes run at once against one host.
+const cipherWorkers = 8
+
+// weakCipherMarkers are the parts of a suite name that mark it deprecated.
+var weakCipherMarkers = []string{"RC4", "3DES", "NULL"}
+
+// enumerateCiphers returns the TLS 1.0 to 1.2 cipher suites host accepts on
+// port, in the order Go lists them, by offering each suite on its own in a
+// separate handshake. TLS 1.3 suites cannot be offered one by one and are
+// not tested, and Go cannot offer NULL suites at all.
+func enumerateCiphers(host string, port int) []uint16 {
+	var suites []uint16
+	for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		for _, v := range suite.SupportedVersions {
+			if v <= tls.VersionTLS12 {
+				suites = append(suites, suite.ID)
+				break
+			}
+		}
+	}
+
+	accepted := make([]bool, len(suites))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range cipherWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				accepted[i] = acceptsCipher(host, port, suites[i])
+			}
+		}()
+	}
+	for i := range suites {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	var result []uint16
+	for i, ok := range accepted {
+		if ok {
+			result = append(result, suites[i])
+		}
+	}
+	return result
+}
+
+// acceptsCipher reports whether a handshake offering only suite succeeds.
+func acceptsCipher(host string, port int, suite uint16) bool {
+	dialer := newDialer()
+	dialer.Timeout = tlsTimeout
+	conn, err := tls.DialWithDialer(dialer, "tcp", net.JoinHostPort(host, strconv.Itoa(port)), &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: true,
+		MinVersion:         tls.VersionTLS10,
+		MaxVersion:         tls.VersionTLS12,
+		CipherSuites:       []uint16{suite},
+	})
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// weakCipher reports whether suite is an RC4, 3DES or NULL suite.
+func weakCipher(suite uint16) bool {
+	name := tls.CipherSuiteName(suite)
+	for _, marker := range weakCipherMarkers {
+		if strings.Contains(name, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkCiphers lists the cipher suites each host accepts on port 443 and
+// flags the deprecated ones.
+func checkCiphers(hosts []string) {
+	for _, host := range hosts {
+		suites := enumerateCiphers(host, 443)
+		if len(suites) == 0 {
+			continue
+		}
+		names := make([]string, len(suites))
+		for i, suite := range suites {
+			names[i] = tls.CipherSuiteName(suite)
+		}
+		fmt.Fprintf(status, " - [CIPHERS] %s: %s\n", host, strings.Join(names, ", "))
+		for _, suite := range suites {
+			if weakCipher(suite) {
+				fmt.Fprintf(status, " - [WEAK-CIPHER] %s accepts %s\n", host, tls.CipherSuiteName(suite))
+			}
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// cloudSuffixes maps the CNAME suffixes of hosted cloud services to the
+// provider and service behind them.
+var cloudSuffixes = map[string]string{
+	".cloudfront.net":           "AWS/CloudFront",
+	".elasticbeanstalk.com":     "AWS/Elastic Beanstalk",
+	".elb.amazonaws.com":        "AWS/ELB",
+	".awsglobalaccelerator.com": "AWS/Global Accelerator",
+	".amplifyapp.com":           "AWS/Amplify",
+	".awsapprunner.com":         "AWS/App Runner",
+	".azurewebsites.net":        "Azure/App Service",
+	".cloudapp.net":             "Azure/Cloud Services",
+	".cloudapp.azure.com":       "Azure/Virtual Machines",
+	".blob.core.windows.net":    "Azure/Blob Storage",
+	".azureedge.net":            "Azure/CDN",
+	".azurefd.net":              "Azure/Front Door",
+	".trafficmanager.net":       "Azure/Traffic Manager",
+	".azure-api.net":            "Azure/API Management",
+	".azurestaticapps.net":      "Azure/Static Web Apps",
+	".appspot.com":              "GCP/App Engine",
+	".run.app":                  "GCP/Cloud Run",
+	".cloudfunctions.net":       "GCP/Cloud Functions",
+	".storage.googleapis.com":   "GCP/Cloud Storage",
+	".web.app":                  "GCP/Firebase Hosting",
+	".firebaseapp.com":          "GCP/Firebase Hosting",
+	".ondigitalocean.app":       "DigitalOcean/App Platform",
+	".digitaloceanspaces.com":   "DigitalOcean/Spaces",
+	".herokuapp.com":            "Heroku",
+	".herokudns.com":            "Heroku",
+	".netlify.app":              "Netlify",
+	".vercel-dns.com":           "Vercel",
+	".github.io":                "GitHub/Pages",
+	".fly.dev":                  "Fly.io",
+	".oraclecloud.com":          "Oracle Cloud",
+	".aliyuncs.com":             "Alibaba Cloud",
+	".myqcloud.com":             "Tencent Cloud",
+}
+
+// awsServiceLabels names the AWS services whose hostnames put the region
+// after the service label, as in bucket.s3.eu-west-1.amazonaws.com.
+var awsServiceLabels = map[string]string{
+	"s3":          "AWS/S3",
+	"s3-website":  "AWS/S3 Website",
+	"execute-api": "AWS/API Gateway",
+	"lambda-url":  "AWS/Lambda",
+}
+
+// detectCloudProvider names the cloud service a CNAME target belongs to as
+// "Provider/Service", or "" when it matches none.
+func detectCloudProvider(cname string) string {
+	name := "." + strings.ToLower(strings.TrimSuffix(cname, "."))
+	var match string
+	for suffix := range cloudSuffixes {
+		if strings.HasSuffix(name, suffix) && len(suffix) > len(match) {
+			match = suffix
+		}
+	}
+	if match != "" {
+		return cloudSuffixes[match]
+	}
+	if rest, ok := strings.CutSuffix(name, ".amazonaws.com"); ok {
+		for _, label := range strings.Split(rest, ".") {
+			// s3-website-us-east-1 is the older dashed form of the website endpoint
+			if strings.HasPrefix(label, "s3-website") {
+				return awsServiceLabels["s3-website"]
+			}
+			// EC2 public names end in compute-1 (us-east-1) or compute
+			if label == "compute" || label == "compute-1" {
+				return "AWS/EC2"
+			}
+			if provider, ok := awsServiceLabels[label]; ok {
+				return provider
+			}
+		}
+	}
+	return ""
+}
+
+// cloudProviderOf resolves the canonical name of host and names the cloud
+// service it points at, judging host itself when it has no CNAME.
+func cloudProviderOf(host string) string {
+	cname, err := resolver.LookupCNAME(context.Background(), host)
+	if err != nil || cname == "" {
+		cname = host
+	}
+	return detectCloudProvider(cname)
+}
+
+// checkCloudProviders prints the cloud service behind every host that
+// points at one and returns the services keyed by host.
+func checkCloudProviders(hosts []string) map[string]string {
+	providers := make(map[string]string)
+	for _, host := range hosts {
+		if provider := cloudProviderOf(host); provider != "" {
+			providers[host] = provider
+		}
+	}
+
+	sorted := make([]string, 0, len(providers))
+	for host := range providers {
+		sorted = append(sorted, host)
+	}
+	sort.Strings(sorted)
+	for _, host := range sorted {
+		fmt.Fprintf(status, " - [CLOUD: %s] %s\n", providers[host], host)
+	}
+	return providers
+}
@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestDetectCloudProvider(t *testing.T) {
+	tests := []struct {
+		cname string
+		want  string
+	}{
+		{"d111111abcdef8.cloudfront.net.", "AWS/CloudFront"},
+		{"MyApp.AzureWebsites.NET", "Azure/App Service"},
+		{"my-env.eu-west-1.elasticbeanstalk.com", "AWS/Elastic Beanstalk"},
+		{"internal-lb-123.us-east-1.elb.amazonaws.com", "AWS/ELB"},
+		{"backups.blob.core.windows.net", "Azure/Blob Storage"},
+		{"project.appspot.com", "GCP/App Engine"},
+		{"bucket.s3.eu-west-1.amazonaws.com", "AWS/S3"},
+		{"bucket.s3-website-us-east-1.amazonaws.com", "AWS/S3 Website"},
+		{"bucket.s3-website.eu-central-1.amazonaws.com", "AWS/S3 Website"},
+		{"abc123.execute-api.us-east-1.amazonaws.com", "AWS/API Gateway"},
+		{"abc123.lambda-url.us-east-1.on.aws", ""},
+		{"ec2-192-0-2-1.compute-1.amazonaws.com", "AWS/EC2"},
+		{"ec2-192-0-2-1.eu-west-1.compute.amazonaws.com", "AWS/EC2"},
+		{"notcloudfront.net", ""},
+		{"www.example.com", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := detectCloudProvider(tt.cname); got != tt.want {
+			t.Errorf("detectCloudProvider(%q) = %q, want %q", tt.cname, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Team Cymru answers IP to ASN queries as TXT records under these zones.
+const (
+	cymruOrigin4 = "origin.asn.cymru.com"
+	cymruOrigin6 = "origin6.asn.cymru.com"
+)
+
+// CymruASN is the origin of the most specific announced prefix covering an
+// address, as Team Cymru reports it. Prefixes announced by more than one
+// AS list all of them.
+type CymruASN struct {
+	ASNs      []int
+	Prefix    string
+	Country   string
+	Registry  string
+	Allocated string
+}
+
+// cymruASNLookup queries the TXT record for ip under the Team Cymru origin
+// zone, in the nibble or octet reversed form used for PTR names, and
+// parses its "ASN | prefix | country | registry | allocated" answer.
+func cymruASNLookup(ip net.IP) (CymruASN, error) {
+	zone, suffix := cymruOrigin6, ".ip6.arpa"
+	bits := 128
+	if ip.To4() != nil {
+		zone, suffix = cymruOrigin4, ".in-addr.arpa"
+		bits = 32
+	}
+	reversed := strings.TrimSuffix(reverseZoneName(&net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}), suffix)
+	records, err := resolver.LookupTXT(context.Background(), reversed+"."+zone)
+	if err != nil {
+		return CymruASN{}, fmt.Errorf("Cymru lookup failed: %w", err)
+	}
+
+	var best CymruASN
+	bestLen := -1
+	for _, record := range records {
+		asn, err := parseCymruOrigin(record)
+		if err != nil {
+			continue
+		}
+		if _, prefix, err := net.ParseCIDR(asn.Prefix); err == nil {
+			if ones, _ := prefix.Mask.Size(); ones > bestLen {
+				best, bestLen = asn, ones
+			}
+		}
+	}
+	if bestLen < 0 {
+		return CymruASN{}, fmt.Errorf("no usable Cymru origin record for %s", ip)
+	}
+	return best, nil
+}
+
+// parseCymruOrigin parses one origin TXT record.
+func parseCymruOrigin(record string) (CymruASN, error) {
+	fields := strings.Split(record, "|")
+	if len(fields) < 5 {
+		return CymruASN{}, fmt.Errorf("malformed Cymru record %q", record)
+	}
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+	result := CymruASN{Prefix: fields[1], Country: fields[2], Registry: fields[3], Allocated: fields[4]}
+	for _, field := range strings.Fields(fields[0]) {
+		asn, err := strconv.Atoi(field)
+		if err != nil {
+			return CymruASN{}, fmt.Errorf("malformed ASN %q in Cymru record", field)
+		}
+		result.ASNs = append(result.ASNs, asn)
+	}
+	return result, nil
+}
+
+// checkCymruASN prints the origin AS of every address the hosts resolve
+// to and returns the results keyed by address.
+func checkCymruASN(hosts []string) map[string]CymruASN {
+	results := make(map[string]CymruASN)
+	failed := make(map[string]bool)
+	for _, host := range hosts {
+		for _, ip := range lookupIPs(host) {
+			result, ok := results[ip.String()]
+			if !ok {
+				if failed[ip.String()] {
+					continue
+				}
+				var err error
+				result, err = cymruASNLookup(ip)
+				if err != nil {
+					failed[ip.String()] = true
+					log.Printf("ASN lookup for %s failed: %v\n", ip, err)
+					continue
+				}
+				results[ip.String()] = result
+			}
+			var asns []string
+			for _, asn := range result.ASNs {
+				asns = append(asns, "AS"+strconv.Itoa(asn))
+			}
+			fmt.Fprintf(status, " - [ASN] %s (%s) %s %s %s %s\n", host, ip, strings.Join(asns, ","), result.Prefix, result.Country, result.Registry)
+		}
+	}
+	return results
+}
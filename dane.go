@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const smtpTimeout = 15 * time.Second
+
+// DANE outcomes.
+const (
+	daneValid   = "valid"
+	daneInvalid = "invalid"
+	daneAbsent  = "absent"
+	daneError   = "error"
+)
+
+// DANEResult is the outcome of checking an SMTP server against its TLSA
+// records.
+type DANEResult struct {
+	Host   string `json:"host"`
+	Port   int    `json:"port"`
+	Status string `json:"status"`
+	TLSA   int    `json:"tlsa_records"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type tlsaRecord struct {
+	usage, selector, matching uint8
+	data                      []byte
+}
+
+// validateDANE fetches the TLSA records of _port._tcp.mxHost, connects to
+// the server with STARTTLS and checks whether any record matches the
+// certificate chain it presents. The TLSA lookup is not DNSSEC validated,
+// so a valid result only proves the records and the certificate agree.
+func validateDANE(mxHost string, port int) DANEResult {
+	result := DANEResult{Host: mxHost, Port: port}
+	records, err := lookupTLSA(fmt.Sprintf("_%d._tcp.%s", port, mxHost))
+	if err != nil {
+		result.Status, result.Detail = daneError, err.Error()
+		return result
+	}
+	result.TLSA = len(records)
+	if len(records) == 0 {
+		result.Status = daneAbsent
+		return result
+	}
+
+	chain, err := startTLSChain(mxHost, port)
+	if err != nil {
+		result.Status, result.Detail = daneError, err.Error()
+		return result
+	}
+	for _, record := range records {
+		if record.matches(chain) {
+			result.Status = daneValid
+			return result
+		}
+	}
+	result.Status, result.Detail = daneInvalid, "no TLSA record matches the presented certificate chain"
+	return result
+}
+
+func lookupTLSA(name string) ([]tlsaRecord, error) {
+	resp, err := rawQuery(name, typeTLSA, "")
+	if err != nil {
+		return nil, err
+	}
+	var records []tlsaRecord
+	for _, rr := range resp.Answers {
+		body, ok := rr.Body.(*dnsmessage.UnknownResource)
+		if !ok || rr.Header.Type != typeTLSA || len(body.Data) < 3 {
+			continue
+		}
+		records = append(records, tlsaRecord{body.Data[0], body.Data[1], body.Data[2], body.Data[3:]})
+	}
+	return records, nil
+}
+
+func startTLSChain(host string, port int) ([]*x509.Certificate, error) {
+	dialer := newDialer()
+	dialer.Timeout = smtpTimeout
+	conn, err := dialer.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	defer client.Close()
+	if err := client.Hello("sub-sniax.invalid"); err != nil {
+		return nil, err
+	}
+	if ok, _ := client.Extension("STARTTLS"); !ok {
+		return nil, fmt.Errorf("%s does not offer STARTTLS", host)
+	}
+	// Trust comes from the TLSA records, not from the WebPKI
+	if err := client.StartTLS(&tls.Config{ServerName: host, InsecureSkipVerify: true}); err != nil {
+		return nil, err
+	}
+	state, _ := client.TLSConnectionState()
+	client.Quit()
+	if len(state.PeerCertificates) == 0 {
+		return nil, fmt.Errorf("%s presented no certificate", host)
+	}
+	return state.PeerCertificates, nil
+}
+
+// matches reports whether the record matches chain. End entity usages
+// (1 and 3) only match the leaf, trust anchor usages (0 and 2) any
+// certificate in the chain.
+func (r tlsaRecord) matches(chain []*x509.Certificate) bool {
+	candidates := chain
+	if r.usage == 1 || r.usage == 3 {
+		candidates = chain[:1]
+	}
+	for _, cert := range candidates {
+		var selected []byte
+		switch r.selector {
+		case 0:
+			selected = cert.Raw
+		case 1:
+			selected = cert.RawSubjectPublicKeyInfo
+		default:
+			return false
+		}
+		var digest []byte
+		switch r.matching {
+		case 0:
+			digest = selected
+		case 1:
+			sum := sha256.Sum256(selected)
+			digest = sum[:]
+		case 2:
+			sum := sha512.Sum512(selected)
+			digest = sum[:]
+		default:
+			return false
+		}
+		if bytes.Equal(digest, r.data) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkDANE validates every MX host of domain on port 25 and prints the
+// ones without DANE separately from the ones that fail it.
+func checkDANE(domain string) []DANEResult {
+	mxs, err := resolver.LookupMX(context.Background(), domain)
+	if err != nil {
+		log.Printf("Failed to get MX records for %s: %v\n", domain, err)
+		return nil
+	}
+	var results []DANEResult
+	for _, mx := range mxs {
+		result := validateDANE(strings.TrimSuffix(mx.Host, "."), 25)
+		results = append(results, result)
+		switch result.Status {
+		case daneValid:
+			fmt.Fprintf(status, " - [DANE-VALID] %s matches %d TLSA record(s)\n", result.Host, result.TLSA)
+		case daneInvalid:
+			fmt.Fprintf(status, " - [DANE-INVALID] %s: %s\n", result.Host, result.Detail)
+		case daneAbsent:
+			fmt.Fprintf(status, " - [DANE-ABSENT] %s publishes no TLSA record\n", result.Host)
+		default:
+			log.Printf("DANE check of %s failed: %s\n", result.Host, result.Detail)
+		}
+	}
+	return results
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// cloudRanges lists large allocations of the cloud providers whose
+// addresses get released and reassigned to other customers. They are a
+// sample of the published ranges, not the full lists.
+var cloudRanges = map[string][]netip.Prefix{
+	"AWS": prefixes(
+		"3.0.0.0/9", "18.128.0.0/9", "34.192.0.0/10", "35.152.0.0/13", "44.192.0.0/10",
+		"52.0.0.0/10", "54.64.0.0/11", "54.144.0.0/12", "13.48.0.0/13", "99.77.0.0/16",
+	),
+	"GCP": prefixes(
+		"34.64.0.0/10", "35.184.0.0/13", "35.192.0.0/12", "35.208.0.0/12", "104.154.0.0/15",
+		"104.196.0.0/14", "130.211.0.0/16", "146.148.0.0/17",
+	),
+	"Azure": prefixes(
+		"13.64.0.0/11", "20.36.0.0/14", "20.40.0.0/13", "40.64.0.0/10", "52.224.0.0/11",
+		"104.40.0.0/13", "137.116.0.0/15", "168.61.0.0/16",
+	),
+	"DigitalOcean": prefixes(
+		"64.225.0.0/16", "104.131.0.0/16", "134.209.0.0/16", "137.184.0.0/16", "138.68.0.0/16",
+		"142.93.0.0/16", "143.198.0.0/16", "146.190.0.0/16", "159.65.0.0/16", "159.89.0.0/16",
+		"161.35.0.0/16", "164.90.0.0/16", "165.227.0.0/16", "167.99.0.0/16", "178.62.0.0/16",
+		"188.166.0.0/16", "206.189.0.0/16",
+	),
+}
+
+// unclaimedMarkers are snippets of the default pages cloud front ends
+// serve for a name no customer has configured.
+var unclaimedMarkers = []string{
+	"NoSuchBucket",
+	"The specified bucket does not exist",
+	"404 Web Site not found",
+	"The resource you are looking for has been removed",
+	"Error 404 - Web app not found",
+	"There is no app configured at that hostname",
+	"Domain not configured",
+}
+
+// danglingAttempts is how many rounds of connections to ports 80 and 443
+// must all fail before a cloud address counts as unreachable, so a single
+// dropped packet does not flag a live host.
+const danglingAttempts = 3
+
+// DanglingFinding is the verdict on one address of a subdomain. Provider
+// is empty when the address is not in a known cloud range.
+type DanglingFinding struct {
+	Subdomain string
+	IP        string
+	Provider  string
+	Dangling  bool
+	Reason    string
+}
+
+// cloudProvider names the cloud provider whose range contains ip.
+func cloudProvider(ip net.IP) string {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return ""
+	}
+	addr = addr.Unmap()
+	for provider, ranges := range cloudRanges {
+		for _, prefix := range ranges {
+			if prefix.Contains(addr) {
+				return provider
+			}
+		}
+	}
+	return ""
+}
+
+// detectDanglingDNS checks whether subdomain points at a cloud address
+// that may no longer belong to whoever set up the record: one that
+// serves the provider's unclaimed page for the name, or that answers
+// neither HTTP nor HTTPS in danglingAttempts rounds. Any other response,
+// an error page included, leaves the record alone. The first cloud
+// address decides.
+func detectDanglingDNS(subdomain string) DanglingFinding {
+	finding := DanglingFinding{Subdomain: subdomain}
+	for _, ip := range lookupIPs(subdomain) {
+		if provider := cloudProvider(ip); provider != "" {
+			finding.IP, finding.Provider = ip.String(), provider
+			break
+		}
+	}
+	if finding.Provider == "" {
+		return finding
+	}
+
+	if !webReachable(finding.IP) {
+		finding.Dangling = true
+		finding.Reason = fmt.Sprintf("no response on ports 80 and 443 in %d attempts", danglingAttempts)
+		return finding
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "http://"+net.JoinHostPort(finding.IP, "80")+"/", nil)
+	if err != nil {
+		return finding
+	}
+	req.Host = subdomain
+	resp, err := probeClient.Do(req)
+	if err != nil {
+		return finding
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	for _, marker := range unclaimedMarkers {
+		if strings.Contains(string(body), marker) {
+			finding.Dangling = true
+			finding.Reason = fmt.Sprintf("serves an unclaimed page (%q)", marker)
+			break
+		}
+	}
+	return finding
+}
+
+// webReachable reports whether ip accepts a connection on port 80 or 443
+// in any of danglingAttempts rounds, a second apart.
+func webReachable(ip string) bool {
+	for attempt := range danglingAttempts {
+		if attempt > 0 {
+			time.Sleep(time.Second)
+		}
+		for _, port := range []string{"80", "443"} {
+			dialer := newDialer()
+			dialer.Timeout = dnsQueryTimeout
+			conn, err := dialer.Dial("tcp", net.JoinHostPort(ip, port))
+			if err == nil {
+				conn.Close()
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkDangling prints every host whose cloud address looks deprovisioned.
+// Each is a lead to confirm by claiming the address, not proof.
+func checkDangling(hosts []string) []DanglingFinding {
+	var findings []DanglingFinding
+	for _, host := range hosts {
+		finding := detectDanglingDNS(host)
+		if !finding.Dangling {
+			continue
+		}
+		findings = append(findings, finding)
+		fmt.Fprintf(status, " - [DANGLING: %s] %s -> %s, %s, possibly released\n", finding.Provider, host, finding.IP, finding.Reason)
+	}
+	return findings
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/noob6t5/sub_sniaX/subsniax"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const dnsQueryTimeout = 5 * time.Second
+
+// Record types that dnsmessage has no constant for.
+const (
+	typeDS     dnsmessage.Type = 43
+	typeRRSIG  dnsmessage.Type = 46
+	typeNSEC   dnsmessage.Type = 47
+	typeDNSKEY dnsmessage.Type = 48
+	typeNSEC3  dnsmessage.Type = 50
+	typeTLSA   dnsmessage.Type = 52
+	typeIXFR                   = subsniax.TypeIXFR
+	typeCAA    dnsmessage.Type = 257
+)
+
+// dnsClass is the class of every query the tool builds itself: raw
+// queries, zone transfers and amplification probes. Lookups made through
+// net.Resolver are always IN.
+var dnsClass = dnsmessage.ClassINET
+
+var dnsClasses = map[string]dnsmessage.Class{
+	"INET":   dnsmessage.ClassINET,
+	"IN":     dnsmessage.ClassINET,
+	"CHAOS":  dnsmessage.ClassCHAOS,
+	"CH":     dnsmessage.ClassCHAOS,
+	"HESIOD": dnsmessage.ClassHESIOD,
+	"HS":     dnsmessage.ClassHESIOD,
+}
+
+// parseDNSClass returns the class named by s, case-insensitively.
+func parseDNSClass(s string) (dnsmessage.Class, error) {
+	class, ok := dnsClasses[strings.ToUpper(s)]
+	if !ok {
+		return 0, fmt.Errorf("unknown DNS class %q, use INET, CHAOS or HESIOD", s)
+	}
+	return class, nil
+}
+
+var typeNames = map[dnsmessage.Type]string{
+	dnsmessage.TypeA:     "A",
+	dnsmessage.TypeNS:    "NS",
+	dnsmessage.TypeCNAME: "CNAME",
+	dnsmessage.TypeSOA:   "SOA",
+	dnsmessage.TypePTR:   "PTR",
+	dnsmessage.TypeMX:    "MX",
+	dnsmessage.TypeTXT:   "TXT",
+	dnsmessage.TypeAAAA:  "AAAA",
+	dnsmessage.TypeSRV:   "SRV",
+	dnsmessage.TypeOPT:   "OPT",
+	dnsmessage.TypeHINFO: "HINFO",
+	dnsmessage.TypeAXFR:  "AXFR",
+	typeIXFR:             "IXFR",
+	dnsmessage.TypeALL:   "ANY",
+	typeDS:               "DS",
+	typeRRSIG:            "RRSIG",
+	typeNSEC:             "NSEC",
+	typeDNSKEY:           "DNSKEY",
+	typeNSEC3:            "NSEC3",
+	typeTLSA:             "TLSA",
+	typeCAA:              "CAA",
+}
+
+// typeName returns the mnemonic for t, or the RFC 3597 TYPEnn form for
+// types without one.
+func typeName(t dnsmessage.Type) string {
+	if name, ok := typeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("TYPE%d", uint16(t))
+}
+
+// systemNameserver returns the first nameserver listed in /etc/resolv.conf,
+// falling back to the local resolver like the Go runtime does.
+func systemNameserver() string {
+	file, err := os.Open("/etc/resolv.conf")
+	if err != nil {
+		return "127.0.0.1:53"
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			return net.JoinHostPort(fields[1], "53")
+		}
+	}
+	return "127.0.0.1:53"
+}
+
+// rawQuery sends a single question and returns the full response message.
+// An empty server routes the query through the configured resolver, a
+// "host" or "host:port" server is queried directly.
+func rawQuery(name string, qtype dnsmessage.Type, server string) (*dnsmessage.Message, error) {
+	qname, err := dnsmessage.NewName(dnsName(name))
+	if err != nil {
+		return nil, fmt.Errorf("invalid name %q: %w", name, err)
+	}
+	msg := dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID:               uint16(rand.Intn(1 << 16)),
+			RecursionDesired: true,
+			OpCode:           OpCodeQuery,
+		},
+		Questions: []dnsmessage.Question{
+			{Name: qname, Type: qtype, Class: dnsClass},
+		},
+	}
+	query, err := msg.Pack()
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack query for %s: %w", name, err)
+	}
+
+	resp, err := exchange(query, "udp", server)
+	if err == nil && resp.Header.Truncated {
+		resp, err = exchange(query, "tcp", server)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if resp.Header.ID != msg.Header.ID {
+		return nil, errors.New("DNS response ID mismatch")
+	}
+	return resp, nil
+}
+
+func exchange(query []byte, network, server string) (*dnsmessage.Message, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dnsQueryTimeout)
+	defer cancel()
+
+	conn, err := dialDNS(ctx, network, server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(dnsQueryTimeout))
+
+	var buf []byte
+	if _, ok := conn.(net.PacketConn); ok {
+		if _, err := conn.Write(query); err != nil {
+			return nil, err
+		}
+		buf = make([]byte, 65535)
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		buf = buf[:n]
+	} else {
+		framed := make([]byte, 2+len(query))
+		binary.BigEndian.PutUint16(framed, uint16(len(query)))
+		copy(framed[2:], query)
+		if _, err := conn.Write(framed); err != nil {
+			return nil, err
+		}
+		var length [2]byte
+		if _, err := io.ReadFull(conn, length[:]); err != nil {
+			return nil, err
+		}
+		buf = make([]byte, binary.BigEndian.Uint16(length[:]))
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			return nil, err
+		}
+	}
+
+	var resp dnsmessage.Message
+	if err := resp.Unpack(buf); err != nil {
+		return nil, fmt.Errorf("failed to unpack DNS response: %w", err)
+	}
+	return &resp, nil
+}
+
+func dialDNS(ctx context.Context, network, server string) (net.Conn, error) {
+	if server == "" {
+		if resolver.Dial != nil {
+			return resolver.Dial(ctx, network, systemNameserver())
+		}
+		server = systemNameserver()
+	}
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	return newDialer().DialContext(ctx, network, server)
+}
+
+// dnsName returns name in fully qualified form.
+func dnsName(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
+
+// soaFor returns the owner of the SOA record covering name, taken from the
+// answer section when name is a zone apex and from the authority section
+// otherwise.
+func soaFor(name string) (string, *dnsmessage.SOAResource, error) {
+	resp, err := rawQuery(name, dnsmessage.TypeSOA, "")
+	if err != nil {
+		return "", nil, err
+	}
+	for _, section := range [][]dnsmessage.Resource{resp.Answers, resp.Authorities} {
+		for _, rr := range section {
+			if soa, ok := rr.Body.(*dnsmessage.SOAResource); ok {
+				return strings.TrimSuffix(rr.Header.Name.String(), "."), soa, nil
+			}
+		}
+	}
+	return "", nil, fmt.Errorf("no SOA record found for %s", name)
+}
+
+// detectZoneCut reports whether subdomain is the apex of a zone delegated
+// away from its parent, and if so which nameserver is authoritative for it.
+func detectZoneCut(subdomain string) (bool, string, error) {
+	_, parent, ok := strings.Cut(subdomain, ".")
+	if !ok || parent == "" {
+		return false, "", fmt.Errorf("%s has no parent zone", subdomain)
+	}
+
+	owner, soa, err := soaFor(subdomain)
+	if err != nil {
+		return false, "", err
+	}
+	parentOwner, _, err := soaFor(parent)
+	if err != nil {
+		return false, "", err
+	}
+	if strings.EqualFold(owner, parentOwner) || !strings.EqualFold(owner, subdomain) {
+		return false, "", nil
+	}
+
+	nameServers, err := resolver.LookupNS(context.Background(), subdomain)
+	if err == nil && len(nameServers) > 0 {
+		return true, nameServers[0].Host, nil
+	}
+	return true, strings.TrimSuffix(soa.NS.String(), "."), nil
+}
+
+// lookupIPs resolves host to its unique addresses, ignoring lookup errors.
+func lookupIPs(host string) []net.IP {
+	addrs, err := resolver.LookupIPAddr(context.Background(), host)
+	if err != nil {
+		return nil
+	}
+	ips := make([]net.IP, 0, len(addrs))
+	for _, addr := range addrs {
+		ips = append(ips, addr.IP)
+	}
+	return ips
+}
+
+// resolveFindings fills in the addresses of the findings that have none
+// yet, looking them up with workers at once. Names without an address get
+// an empty list so they are not looked up again.
+func resolveFindings(findings []Finding, workers int) {
+	jobs := make(chan *Finding)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for finding := range jobs {
+				addrs := []string{}
+				for _, ip := range lookupIPs(findingNames([]Finding{*finding})[0]) {
+					addrs = append(addrs, ip.String())
+				}
+				finding.Addrs = addrs
+			}
+		}()
+	}
+	for i := range findings {
+		if findings[i].Addrs == nil {
+			jobs <- &findings[i]
+		}
+	}
+	close(jobs)
+	wg.Wait()
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"os"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// DNSSEC algorithm numbers (RFC 8624).
+var dnssecAlgorithms = map[uint8]string{
+	1:  "RSAMD5",
+	3:  "DSA",
+	5:  "RSASHA1",
+	6:  "DSA-NSEC3-SHA1",
+	7:  "RSASHA1-NSEC3-SHA1",
+	8:  "RSASHA256",
+	10: "RSASHA512",
+	12: "ECC-GOST",
+	13: "ECDSAP256SHA256",
+	14: "ECDSAP384SHA384",
+	15: "ED25519",
+	16: "ED448",
+}
+
+// weakAlgorithms are the algorithms RFC 8624 says must not or should not
+// be used for signing.
+var weakAlgorithms = map[uint8]bool{1: true, 3: true, 5: true, 6: true, 7: true, 12: true}
+
+const minRSABits = 2048
+
+// DNSKEYRecord is a zone's public signing key.
+type DNSKEYRecord struct {
+	Domain    string `json:"domain"`
+	Flags     uint16 `json:"flags"`
+	Protocol  uint8  `json:"protocol"`
+	Algorithm uint8  `json:"algorithm"`
+	KeyTag    uint16 `json:"key_tag"`
+	KSK       bool   `json:"ksk"`
+	KeyBits   int    `json:"key_bits,omitempty"`
+	PublicKey string `json:"public_key"`
+}
+
+// collectDNSKEY queries ns for the DNSKEY set of domain. An empty ns uses
+// the configured resolver.
+func collectDNSKEY(domain, ns string) ([]DNSKEYRecord, error) {
+	resp, err := rawQuery(domain, typeDNSKEY, ns)
+	if err != nil {
+		return nil, err
+	}
+	var keys []DNSKEYRecord
+	for _, rr := range resp.Answers {
+		body, ok := rr.Body.(*dnsmessage.UnknownResource)
+		if !ok || rr.Header.Type != typeDNSKEY || len(body.Data) < 4 {
+			continue
+		}
+		key := DNSKEYRecord{
+			Domain:    domain,
+			KeyTag:    keyTag(body.Data),
+			Flags:     uint16(body.Data[0])<<8 | uint16(body.Data[1]),
+			Protocol:  body.Data[2],
+			Algorithm: body.Data[3],
+			PublicKey: base64.StdEncoding.EncodeToString(body.Data[4:]),
+		}
+		// The SEP bit marks key signing keys
+		key.KSK = key.Flags&1 != 0
+		if isRSA(key.Algorithm) {
+			key.KeyBits = rsaModulusBits(body.Data[4:])
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// keyTag computes the RFC 4034 Appendix B key tag of the DNSKEY rdata.
+func keyTag(rdata []byte) uint16 {
+	if rdata[3] == 1 {
+		// RSAMD5 keys take the tag from the end of the modulus
+		if len(rdata) < 7 {
+			return 0
+		}
+		return uint16(rdata[len(rdata)-3])<<8 | uint16(rdata[len(rdata)-2])
+	}
+	var sum uint32
+	for i, b := range rdata {
+		if i&1 == 0 {
+			sum += uint32(b) << 8
+		} else {
+			sum += uint32(b)
+		}
+	}
+	sum += sum >> 16 & 0xffff
+	return uint16(sum)
+}
+
+func isRSA(alg uint8) bool {
+	switch alg {
+	case 1, 5, 7, 8, 10:
+		return true
+	}
+	return false
+}
+
+// rsaModulusBits returns the modulus size of an RFC 3110 RSA public key.
+func rsaModulusBits(key []byte) int {
+	if len(key) < 1 {
+		return 0
+	}
+	expLen, off := int(key[0]), 1
+	if expLen == 0 {
+		if len(key) < 3 {
+			return 0
+		}
+		expLen, off = int(key[1])<<8|int(key[2]), 3
+	}
+	modulus := key[min(off+expLen, len(key)):]
+	for len(modulus) > 0 && modulus[0] == 0 {
+		modulus = modulus[1:]
+	}
+	if len(modulus) == 0 {
+		return 0
+	}
+	bits := (len(modulus) - 1) * 8
+	for b := modulus[0]; b != 0; b >>= 1 {
+		bits++
+	}
+	return bits
+}
+
+// weakness describes why key is weak, or returns "" for a sound key.
+func (key DNSKEYRecord) weakness() string {
+	if weakAlgorithms[key.Algorithm] {
+		return "deprecated algorithm"
+	}
+	if isRSA(key.Algorithm) && key.KeyBits < minRSABits {
+		return fmt.Sprintf("%d-bit RSA key", key.KeyBits)
+	}
+	return ""
+}
+
+func algorithmName(alg uint8) string {
+	if name, ok := dnssecAlgorithms[alg]; ok {
+		return name
+	}
+	return fmt.Sprintf("ALG%d", alg)
+}
+
+// checkDNSKEY prints the DNSKEY set of domain as served by its first
+// nameserver and flags weak keys. In -json mode every key is also written
+// as a JSON line, public key included.
+func checkDNSKEY(domain string, output *os.File) []DNSKEYRecord {
+	var ns string
+	if nameServers, err := resolver.LookupNS(context.Background(), domain); err == nil && len(nameServers) > 0 {
+		ns = nameServers[0].Host
+	}
+	keys, err := collectDNSKEY(domain, ns)
+	if err != nil {
+		log.Printf("Failed to query DNSKEY for %s: %v\n", domain, err)
+		return nil
+	}
+	if len(keys) == 0 {
+		fmt.Fprintf(status, " - %s publishes no DNSKEY records\n", domain)
+	}
+	for _, key := range keys {
+		role := "ZSK"
+		if key.KSK {
+			role = "KSK"
+		}
+		line := fmt.Sprintf(" - %s %s %s tag %d", role, algorithmName(key.Algorithm), domain, key.KeyTag)
+		if key.KeyBits > 0 {
+			line += fmt.Sprintf(" (%d bits)", key.KeyBits)
+		}
+		if reason := key.weakness(); reason != "" {
+			line = fmt.Sprintf(" - [WEAK-DNSKEY] %s %s %s: %s", role, algorithmName(key.Algorithm), domain, reason)
+		}
+		fmt.Fprintln(status, line)
+		if jsonOutput {
+			writeJSONLine(key, domain, output)
+		}
+	}
+	return keys
+}
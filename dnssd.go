@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// enumerateDNSSD browses the unicast DNS-SD records of domain (RFC 6763):
+// the service types listed at _services._dns-sd._udp, the instances of
+// each type and the SRV targets of every instance.
+func enumerateDNSSD(domain string) []string {
+	var instances []string
+	for _, service := range ptrTargets("_services._dns-sd._udp." + domain) {
+		instances = append(instances, ptrTargets(service)...)
+	}
+
+	targets := make(map[string]bool)
+	for _, instance := range instances {
+		resp, err := rawQuery(instance, dnsmessage.TypeSRV, "")
+		if err != nil {
+			continue
+		}
+		for _, rr := range resp.Answers {
+			if srv, ok := rr.Body.(*dnsmessage.SRVResource); ok {
+				target := strings.ToLower(strings.TrimSuffix(srv.Target.String(), "."))
+				if target != "" && !targets[target] {
+					targets[target] = true
+					fmt.Fprintf(status, " - [DNS-SD] %s (%s)\n", target, strings.TrimSuffix(instance, "."))
+				}
+			}
+		}
+	}
+
+	result := make([]string, 0, len(targets))
+	for target := range targets {
+		result = append(result, target)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// ptrTargets returns the PTR records of name.
+func ptrTargets(name string) []string {
+	resp, err := rawQuery(name, dnsmessage.TypePTR, "")
+	if err != nil {
+		return nil
+	}
+	var result []string
+	for _, rr := range resp.Answers {
+		if ptr, ok := rr.Body.(*dnsmessage.PTRResource); ok {
+			result = append(result, ptr.PTR.String())
+		}
+	}
+	return result
+}
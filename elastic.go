@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// esBulkSize is the number of documents sent per _bulk request.
+const esBulkSize = 1000
+
+// esDocument is the form a DiscoveryRecord is indexed in.
+type esDocument struct {
+	Timestamp     time.Time `json:"@timestamp"`
+	Name          string    `json:"name"`
+	Type          string    `json:"type,omitempty"`
+	TTL           uint32    `json:"ttl,omitempty"`
+	Data          string    `json:"data,omitempty"`
+	RedirectChain []string  `json:"redirect_chain,omitempty"`
+}
+
+// IndexToElasticsearch indexes records into indexName on the cluster at url
+// through the _bulk API. Credentials can be given in the URL as user:pass.
+// It fails on the first request the cluster rejects or that reports item
+// errors.
+func IndexToElasticsearch(url, indexName string, records []DiscoveryRecord) error {
+	endpoint := strings.TrimSuffix(url, "/") + "/_bulk"
+	// Index names must be lowercase, the default sub_sniaX included
+	indexName = strings.ToLower(indexName)
+	action, err := json.Marshal(map[string]any{"index": map[string]string{"_index": indexName}})
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+
+	for start := 0; start < len(records); start += esBulkSize {
+		var body bytes.Buffer
+		for _, record := range records[start:min(start+esBulkSize, len(records))] {
+			doc := esDocument{
+				Timestamp:     now,
+				Name:          record.Name,
+				TTL:           record.TTL,
+				Data:          record.Data,
+				RedirectChain: record.RedirectChain,
+			}
+			if record.Type != 0 {
+				doc.Type = typeName(record.Type)
+			}
+			line, err := json.Marshal(doc)
+			if err != nil {
+				return err
+			}
+			body.Write(action)
+			body.WriteByte('\n')
+			body.Write(line)
+			body.WriteByte('\n')
+		}
+
+		resp, err := apiClient.Post(endpoint, "application/x-ndjson", &body)
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode/100 != 2 {
+			return fmt.Errorf("elasticsearch returned %s: %s", resp.Status, bytes.TrimSpace(data))
+		}
+
+		var result struct {
+			Errors bool `json:"errors"`
+			Items  []map[string]struct {
+				Error *struct {
+					Reason string `json:"reason"`
+				} `json:"error"`
+			} `json:"items"`
+		}
+		if err := json.Unmarshal(data, &result); err != nil {
+			return fmt.Errorf("unexpected _bulk response: %w", err)
+		}
+		if !result.Errors {
+			continue
+		}
+		var failed int
+		var reason string
+		for _, item := range result.Items {
+			for _, op := range item {
+				if op.Error != nil {
+					failed++
+					reason = op.Error.Reason
+				}
+			}
+		}
+		return fmt.Errorf("elasticsearch rejected %d documents: %s", failed, reason)
+	}
+	return nil
+}
+
+// addressRecords turns subdomains into A and AAAA records by resolving
+// them. Names that no longer resolve are kept as bare records.
+func addressRecords(subdomains []string) []DiscoveryRecord {
+	var records []DiscoveryRecord
+	for _, name := range subdomains {
+		ips := lookupIPs(name)
+		if len(ips) == 0 {
+			records = append(records, DiscoveryRecord{Name: name})
+		}
+		for _, ip := range ips {
+			qtype := dnsmessage.TypeA
+			if ip.To4() == nil {
+				qtype = dnsmessage.TypeAAAA
+			}
+			records = append(records, DiscoveryRecord{Name: name, Type: qtype, Class: dnsmessage.ClassINET, Data: ip.String()})
+		}
+	}
+	return records
+}
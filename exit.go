@@ -0,0 +1,27 @@
+package main
+
+// ExitCode is the process exit status, so scripts can tell an empty result
+// apart from a broken run.
+type ExitCode int
+
+const (
+	ExitSuccess        ExitCode = 0 // finished with results
+	ExitNoResults      ExitCode = 1 // finished but nothing was found
+	ExitConfigError    ExitCode = 2 // bad flags or unusable input/output files
+	ExitNetworkError   ExitCode = 3 // every domain failed to enumerate
+	ExitPartialSuccess ExitCode = 4 // some domains failed to enumerate
+)
+
+// exitCodeFor picks the exit status for a run over total domains, of which
+// failed could not be enumerated, that produced results subdomains.
+func exitCodeFor(total, failed, results int) ExitCode {
+	switch {
+	case failed > 0 && failed == total:
+		return ExitNetworkError
+	case failed > 0:
+		return ExitPartialSuccess
+	case results == 0:
+		return ExitNoResults
+	}
+	return ExitSuccess
+}
@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestExitCodeFor(t *testing.T) {
+	tests := []struct {
+		total, failed, results int
+		want                   ExitCode
+	}{
+		{1, 0, 12, ExitSuccess},
+		{3, 0, 1, ExitSuccess},
+		{1, 0, 0, ExitNoResults},
+		{0, 0, 0, ExitNoResults},
+		{1, 1, 0, ExitNetworkError},
+		{3, 3, 0, ExitNetworkError},
+		{3, 1, 5, ExitPartialSuccess},
+		{3, 2, 0, ExitPartialSuccess},
+	}
+	for _, tt := range tests {
+		if got := exitCodeFor(tt.total, tt.failed, tt.results); got != tt.want {
+			t.Errorf("exitCodeFor(%d, %d, %d) = %d, want %d", tt.total, tt.failed, tt.results, got, tt.want)
+		}
+	}
+}
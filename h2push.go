@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+const h2PushTimeout = 10 * time.Second
+
+// discoverH2Push requests / from host over HTTP/2 with server push enabled
+// and returns the hostnames of every URL the server promised to push. Go's
+// HTTP/2 client always disables push, so the exchange is done by hand with
+// a raw framer.
+func discoverH2Push(host string) ([]string, error) {
+	dialer := newDialer()
+	dialer.Timeout = h2PushTimeout
+	conn, err := tls.DialWithDialer(dialer, "tcp", net.JoinHostPort(host, "443"), &tls.Config{
+		ServerName:         host,
+		NextProtos:         []string{http2.NextProtoTLS},
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if conn.ConnectionState().NegotiatedProtocol != http2.NextProtoTLS {
+		return nil, errors.New("server does not speak HTTP/2")
+	}
+	conn.SetDeadline(time.Now().Add(h2PushTimeout))
+
+	if _, err := conn.Write([]byte(http2.ClientPreface)); err != nil {
+		return nil, err
+	}
+	framer := http2.NewFramer(conn, conn)
+	if err := framer.WriteSettings(http2.Setting{ID: http2.SettingEnablePush, Val: 1}); err != nil {
+		return nil, err
+	}
+
+	var block bytes.Buffer
+	encoder := hpack.NewEncoder(&block)
+	for _, field := range [][2]string{{":method", "GET"}, {":scheme", "https"}, {":authority", host}, {":path", "/"}, {"user-agent", "sub_sniaX"}} {
+		encoder.WriteField(hpack.HeaderField{Name: field[0], Value: field[1]})
+	}
+	err = framer.WriteHeaders(http2.HeadersFrameParam{
+		StreamID:      1,
+		BlockFragment: block.Bytes(),
+		EndStream:     true,
+		EndHeaders:    true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Every header block has to go through the same decoder, the HPACK
+	// dynamic table is shared by the whole connection
+	var promised []url.URL
+	var pushing bool
+	var current url.URL
+	decoder := hpack.NewDecoder(4096, func(f hpack.HeaderField) {
+		if !pushing {
+			return
+		}
+		switch f.Name {
+		case ":scheme":
+			current.Scheme = f.Value
+		case ":authority":
+			current.Host = f.Value
+		case ":path":
+			current.Path = f.Value
+		}
+	})
+	endBlock := func(ended bool) {
+		if ended && pushing {
+			promised = append(promised, current)
+			pushing = false
+		}
+	}
+
+read:
+	for {
+		frame, err := framer.ReadFrame()
+		if err != nil {
+			if len(promised) > 0 {
+				break
+			}
+			return nil, err
+		}
+		switch f := frame.(type) {
+		case *http2.SettingsFrame:
+			if !f.IsAck() {
+				framer.WriteSettingsAck()
+			}
+		case *http2.PingFrame:
+			if !f.IsAck() {
+				framer.WritePing(true, f.Data)
+			}
+		case *http2.PushPromiseFrame:
+			pushing, current = true, url.URL{Scheme: "https", Host: host}
+			decoder.Write(f.HeaderBlockFragment())
+			endBlock(f.HeadersEnded())
+		case *http2.HeadersFrame:
+			decoder.Write(f.HeaderBlockFragment())
+		case *http2.ContinuationFrame:
+			decoder.Write(f.HeaderBlockFragment())
+			endBlock(f.HeadersEnded())
+		case *http2.DataFrame:
+			if f.Length > 0 {
+				framer.WriteWindowUpdate(0, f.Length)
+				framer.WriteWindowUpdate(f.StreamID, f.Length)
+			}
+		case *http2.GoAwayFrame:
+			break read
+		case *http2.RSTStreamFrame:
+			if f.StreamID == 1 {
+				break read
+			}
+		}
+		// The response to / is complete, anything pushed was promised before it
+		if frame.Header().StreamID == 1 && frame.Header().Flags.Has(http2.FlagDataEndStream) {
+			break
+		}
+	}
+
+	for _, u := range promised {
+		fmt.Fprintf(status, " - [H2-PUSH] %s pushes %s\n", host, u.String())
+	}
+	return pushHosts(promised), nil
+}
+
+func pushHosts(promised []url.URL) []string {
+	seen := make(map[string]bool)
+	var hosts []string
+	for _, u := range promised {
+		name := strings.ToLower(u.Hostname())
+		if name != "" && !seen[name] {
+			seen[name] = true
+			hosts = append(hosts, name)
+		}
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// h2PushEnumerate runs discoverH2Push on every host and returns the new
+// subdomains of domain among the pushed resources.
+func h2PushEnumerate(domain string, hosts []string) []string {
+	known := make(map[string]bool, len(hosts))
+	for _, host := range hosts {
+		known[host] = true
+	}
+	var result []string
+	for _, host := range hosts {
+		names, err := discoverH2Push(host)
+		if err != nil {
+			continue
+		}
+		for _, name := range names {
+			if !known[name] && strings.HasSuffix(name, "."+domain) {
+				known[name] = true
+				result = append(result, name)
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// SecurityHeaderReport describes which security headers a host serves and
+// whether they are configured sensibly.
+type SecurityHeaderReport struct {
+	Host                  string      `json:"host"`
+	HSTS                  bool        `json:"hsts"`
+	HSTSIncludeSubDomains bool        `json:"hsts_include_subdomains"`
+	XContentTypeOptions   bool        `json:"x_content_type_options"`
+	XFrameOptions         bool        `json:"x_frame_options"`
+	ContentSecurityPolicy bool        `json:"content_security_policy"`
+	ReferrerPolicy        bool        `json:"referrer_policy"`
+	PermissionsPolicy     bool        `json:"permissions_policy"`
+	Missing               []string    `json:"missing,omitempty"`
+	Score                 int         `json:"score"`
+	Downgrade             string      `json:"downgrade,omitempty"`
+	HPKP                  *HPKPConfig `json:"hpkp,omitempty"`
+}
+
+// HPKPConfig holds the directives of a Public-Key-Pins header. Browsers
+// dropped HPKP, so a host still sending it is reported as deprecated.
+type HPKPConfig struct {
+	Pins              []string `json:"pins"`
+	MaxAge            int      `json:"max_age"`
+	IncludeSubDomains bool     `json:"include_subdomains"`
+	ReportURI         string   `json:"report_uri,omitempty"`
+	ReportOnly        bool     `json:"report_only"`
+}
+
+// SecurityHeadersCheck grades the security headers of resp on a 0-100 scale.
+func SecurityHeadersCheck(resp *http.Response) SecurityHeaderReport {
+	h := resp.Header
+	report := SecurityHeaderReport{Host: resp.Request.URL.Hostname()}
+
+	checks := []struct {
+		name   string
+		weight int
+		ok     *bool
+		valid  bool
+	}{
+		{"Strict-Transport-Security", 20, &report.HSTS, hstsMaxAge(h.Get("Strict-Transport-Security")) > 0},
+		{"HSTS includeSubDomains", 5, &report.HSTSIncludeSubDomains, hasDirective(h.Get("Strict-Transport-Security"), "includesubdomains")},
+		{"X-Content-Type-Options", 15, &report.XContentTypeOptions, strings.EqualFold(strings.TrimSpace(h.Get("X-Content-Type-Options")), "nosniff")},
+		{"X-Frame-Options", 15, &report.XFrameOptions, validFrameOptions(h.Get("X-Frame-Options")) || hasDirective(h.Get("Content-Security-Policy"), "frame-ancestors")},
+		{"Content-Security-Policy", 25, &report.ContentSecurityPolicy, strings.TrimSpace(h.Get("Content-Security-Policy")) != ""},
+		{"Referrer-Policy", 10, &report.ReferrerPolicy, validReferrerPolicy(h.Get("Referrer-Policy"))},
+		{"Permissions-Policy", 10, &report.PermissionsPolicy, strings.TrimSpace(h.Get("Permissions-Policy")) != ""},
+	}
+	for _, check := range checks {
+		*check.ok = check.valid
+		if check.valid {
+			report.Score += check.weight
+		} else {
+			report.Missing = append(report.Missing, check.name)
+		}
+	}
+	return report
+}
+
+// parseHPKP parses the pin-sha256, max-age, includeSubDomains and
+// report-uri directives of a Public-Key-Pins header value.
+func parseHPKP(header string) HPKPConfig {
+	var config HPKPConfig
+	for _, directive := range strings.Split(header, ";") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		arg = strings.Trim(strings.TrimSpace(arg), `"`)
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "pin-sha256":
+			if arg != "" {
+				config.Pins = append(config.Pins, arg)
+			}
+		case "max-age":
+			if age, err := strconv.Atoi(arg); err == nil {
+				config.MaxAge = age
+			}
+		case "includesubdomains":
+			config.IncludeSubDomains = true
+		case "report-uri":
+			config.ReportURI = arg
+		}
+	}
+	return config
+}
+
+// hpkpHeader returns the pinning policy of h, from Public-Key-Pins or else
+// Public-Key-Pins-Report-Only, or nil when neither is sent.
+func hpkpHeader(h http.Header) *HPKPConfig {
+	value, reportOnly := h.Get("Public-Key-Pins"), false
+	if value == "" {
+		value, reportOnly = h.Get("Public-Key-Pins-Report-Only"), true
+	}
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+	config := parseHPKP(value)
+	config.ReportOnly = reportOnly
+	return &config
+}
+
+func hstsMaxAge(value string) int {
+	for _, directive := range strings.Split(value, ";") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		if strings.EqualFold(name, "max-age") {
+			age, err := strconv.Atoi(strings.Trim(arg, `"`))
+			if err == nil {
+				return age
+			}
+		}
+	}
+	return 0
+}
+
+// hasDirective reports whether a ;-separated header value contains name.
+func hasDirective(value, name string) bool {
+	for _, directive := range strings.Split(value, ";") {
+		fields := strings.Fields(directive)
+		if len(fields) > 0 && strings.EqualFold(fields[0], name) {
+			return true
+		}
+	}
+	return false
+}
+
+func validFrameOptions(value string) bool {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	return value == "DENY" || value == "SAMEORIGIN"
+}
+
+func validReferrerPolicy(value string) bool {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return false
+	}
+	// The last recognised policy in the list wins
+	policies := strings.Split(value, ",")
+	return !strings.EqualFold(strings.TrimSpace(policies[len(policies)-1]), "unsafe-url")
+}
+
+// checkSecurityHeaders fetches the HTTPS root of every host and prints its
+// security header score, in -json mode each report is also written as a
+// JSON line. It returns the new subdomains of domain that HPKP
+// report-uri directives point at, often internal monitoring endpoints.
+func checkSecurityHeaders(domain string, hosts []string, output *os.File) []string {
+	known := make(map[string]bool, len(hosts))
+	for _, host := range hosts {
+		known[host] = true
+	}
+	var reporters []string
+	for _, host := range hosts {
+		resp, err := probeClient.Get("https://" + host + "/")
+		if err != nil {
+			log.Printf("Failed to fetch %s for header check: %v\n", host, err)
+			continue
+		}
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+		resp.Body.Close()
+
+		report := SecurityHeadersCheck(resp)
+		line := fmt.Sprintf(" - [SECURITY-HEADERS] %s score %d/100", host, report.Score)
+		if len(report.Missing) > 0 {
+			line += " missing: " + strings.Join(report.Missing, ", ")
+		}
+		fmt.Fprintln(status, line)
+		if report.Downgrade = checkDowngrade(host, report.HSTS); report.Downgrade != "" {
+			fmt.Fprintf(status, " - [%s] %s\n", report.Downgrade, host)
+		}
+
+		report.HPKP = hpkpHeader(resp.Header)
+		if jsonOutput {
+			writeJSONLine(report, host, output)
+		}
+		if report.HPKP == nil {
+			continue
+		}
+		line = fmt.Sprintf(" - [HPKP-DEPRECATED] %s pins %d keys for %ds", host, len(report.HPKP.Pins), report.HPKP.MaxAge)
+		if report.HPKP.IncludeSubDomains {
+			line += " including subdomains"
+		}
+		if report.HPKP.ReportURI != "" {
+			line += ", reports to " + report.HPKP.ReportURI
+		}
+		fmt.Fprintln(status, line)
+		if u, err := url.Parse(report.HPKP.ReportURI); err == nil {
+			name := strings.ToLower(u.Hostname())
+			if !known[name] && strings.HasSuffix(name, "."+domain) {
+				known[name] = true
+				reporters = append(reporters, name)
+			}
+		}
+	}
+	return reporters
+}
+
+// checkDowngrade probes host over plain HTTP. It returns HTTP-DOWNGRADE-RISK
+// when the site is served there too, HTTPS-ONLY when port 80 refuses the
+// connection and HSTS-PROTECTED when HTTP redirects to HTTPS and the HTTPS
+// side sends HSTS. Timeouts and other failures say nothing about whether
+// HTTP is served and return DOWNGRADE-UNKNOWN. Other outcomes, such as a
+// redirect without HSTS, return "".
+func checkDowngrade(host string, hsts bool) string {
+	resp, err := probeClient.Get("http://" + host + "/")
+	if err != nil {
+		if connectionRefused(err, "80") {
+			return "HTTPS-ONLY"
+		}
+		log.Printf("Failed to fetch %s over HTTP for downgrade check: %v\n", host, err)
+		return "DOWNGRADE-UNKNOWN"
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return "HTTP-DOWNGRADE-RISK"
+	case resp.StatusCode/100 == 3 && strings.HasPrefix(resp.Header.Get("Location"), "https://") && hsts:
+		return "HSTS-PROTECTED"
+	}
+	return ""
+}
+
+// connectionRefused reports whether err is a refused TCP connection to port.
+func connectionRefused(err error, port string) bool {
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) || opErr.Op != "dial" || opErr.Addr == nil || !errors.Is(err, syscall.ECONNREFUSED) {
+		return false
+	}
+	_, p, err := net.SplitHostPort(opErr.Addr.String())
+	return err == nil && p == port
+}
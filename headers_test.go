@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckDowngrade(t *testing.T) {
+	redirect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://"+r.Host+"/", http.StatusMovedPermanently)
+	}))
+	defer redirect.Close()
+	served := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer served.Close()
+	closed := closedAddr(t)
+
+	tests := []struct {
+		host string
+		hsts bool
+		want string
+	}{
+		{strings.TrimPrefix(served.URL, "http://"), true, "HTTP-DOWNGRADE-RISK"},
+		{strings.TrimPrefix(redirect.URL, "http://"), true, "HSTS-PROTECTED"},
+		{strings.TrimPrefix(redirect.URL, "http://"), false, ""},
+		{closed, true, "DOWNGRADE-UNKNOWN"},
+	}
+	for _, tt := range tests {
+		if got := checkDowngrade(tt.host, tt.hsts); got != tt.want {
+			t.Errorf("checkDowngrade(%q, %v) = %q, want %q", tt.host, tt.hsts, got, tt.want)
+		}
+	}
+}
+
+func TestConnectionRefused(t *testing.T) {
+	closed := closedAddr(t)
+	_, port, _ := net.SplitHostPort(closed)
+	_, refused := probeClient.Get("http://" + closed + "/")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+closed+"/", nil)
+	_, timeout := probeClient.Do(req)
+
+	tests := []struct {
+		err  error
+		port string
+		want bool
+	}{
+		{refused, port, true},
+		{refused, "80", false},
+		{timeout, port, false},
+		{nil, "80", false},
+	}
+	for _, tt := range tests {
+		if got := connectionRefused(tt.err, tt.port); got != tt.want {
+			t.Errorf("connectionRefused(%v, %q) = %v, want %v", tt.err, tt.port, got, tt.want)
+		}
+	}
+}
+
+// closedAddr returns a local address nothing listens on.
+func closedAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	return addr
+}
+
+func TestParseHPKP(t *testing.T) {
+	tests := []struct {
+		header string
+		want   HPKPConfig
+	}{
+		{
+			`pin-sha256="cUPcTAZWKaASuYWhhneDttWpY3oBAkE3h2+soZS7sWs="; pin-sha256="M8HztCzM3elUxkcjR2S5P4hhyBNf6lHkmjAHKhpGPWE="; max-age=5184000; includeSubDomains; report-uri="https://hpkp.example.com/report"`,
+			HPKPConfig{Pins: []string{"cUPcTAZWKaASuYWhhneDttWpY3oBAkE3h2+soZS7sWs=", "M8HztCzM3elUxkcjR2S5P4hhyBNf6lHkmjAHKhpGPWE="}, MaxAge: 5184000, IncludeSubDomains: true, ReportURI: "https://hpkp.example.com/report"},
+		},
+		{`PIN-SHA256 = "abc=" ; Max-Age = "600"`, HPKPConfig{Pins: []string{"abc="}, MaxAge: 600}},
+		{`pin-sha256=""; max-age=soon; includesubdomains`, HPKPConfig{IncludeSubDomains: true}},
+		{`report-uri="/report"; unknown=1`, HPKPConfig{ReportURI: "/report"}},
+		{``, HPKPConfig{}},
+	}
+	for _, tt := range tests {
+		if got := parseHPKP(tt.header); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseHPKP(%q) = %+v, want %+v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestHPKPHeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   *HPKPConfig
+	}{
+		{"enforced", http.Header{"Public-Key-Pins": {`pin-sha256="abc="; max-age=60`}}, &HPKPConfig{Pins: []string{"abc="}, MaxAge: 60}},
+		{"report only", http.Header{"Public-Key-Pins-Report-Only": {`pin-sha256="abc="; max-age=60`}}, &HPKPConfig{Pins: []string{"abc="}, MaxAge: 60, ReportOnly: true}},
+		{"enforced wins", http.Header{"Public-Key-Pins": {`max-age=60`}, "Public-Key-Pins-Report-Only": {`max-age=30`}}, &HPKPConfig{MaxAge: 60}},
+		{"blank", http.Header{"Public-Key-Pins": {"  "}}, nil},
+		{"none", http.Header{}, nil},
+	}
+	for _, tt := range tests {
+		if got := hpkpHeader(tt.header); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: hpkpHeader() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// apiClient is used for third-party API lookups. Connections are dialed
+// through newDialer so hostnames resolve via the configured resolver.
+var apiClient = &http.Client{
+	Timeout: 15 * time.Second,
+	Transport: &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return newDialer().DialContext(ctx, network, addr)
+		},
+		TLSHandshakeTimeout: 10 * time.Second,
+	},
+}
+
+// probeClient is used against discovered hosts. Certificates are not
+// verified since many targets are internal or misconfigured, and redirects
+// are not followed so every response is inspected as served. Hosts that
+// answer 429 are paused through probeBackoff.
+var probeClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: headerTransport{backoffTransport{&http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return newDialer().DialContext(ctx, network, addr)
+		},
+		TLSClientConfig:     &tls.Config{InsecureSkipVerify: true},
+		TLSHandshakeTimeout: 10 * time.Second,
+	}, probeBackoff}},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// customHeaders are added to every request sent through probeClient.
+var customHeaders = http.Header{}
+
+type headerTransport struct {
+	base http.RoundTripper
+}
+
+func (t headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if len(customHeaders) > 0 {
+		req = req.Clone(req.Context())
+		for name, values := range customHeaders {
+			// net/http ignores a Host entry in the header map
+			if name == "Host" {
+				req.Host = values[0]
+				continue
+			}
+			req.Header[name] = values
+		}
+	}
+	return t.base.RoundTrip(req)
+}
+
+// parseCustomHeader splits a "Name: Value" pair as given to -header.
+func parseCustomHeader(s string) (string, string, error) {
+	name, value, ok := strings.Cut(s, ":")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return "", "", fmt.Errorf("invalid header %q, expected \"Name: Value\"", s)
+	}
+	if strings.ContainsAny(name, " \t") {
+		return "", "", fmt.Errorf("invalid header name %q", name)
+	}
+	return http.CanonicalHeaderKey(name), strings.TrimSpace(value), nil
+}
+
+// headerFlag implements flag.Value so -header can be repeated.
+type headerFlag struct{}
+
+func (headerFlag) String() string { return "" }
+
+func (headerFlag) Set(s string) error {
+	name, value, err := parseCustomHeader(s)
+	if err != nil {
+		return err
+	}
+	customHeaders.Add(name, value)
+	return nil
+}
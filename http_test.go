@@ -0,0 +1,30 @@
+package main
+
+import "testing"
+
+func TestParseCustomHeader(t *testing.T) {
+	tests := []struct {
+		in, name, value string
+		wantErr         bool
+	}{
+		{"X-Api-Key: secret", "X-Api-Key", "secret", false},
+		{"authorization:Bearer abc", "Authorization", "Bearer abc", false},
+		{"  cookie :  a=1; b=2  ", "Cookie", "a=1; b=2", false},
+		{"X-Forwarded-For: 127.0.0.1:8080", "X-Forwarded-For", "127.0.0.1:8080", false},
+		{"X-Empty:", "X-Empty", "", false},
+		{"no-colon", "", "", true},
+		{": value", "", "", true},
+		{"Bad Name: value", "", "", true},
+		{"", "", "", true},
+	}
+	for _, tt := range tests {
+		name, value, err := parseCustomHeader(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseCustomHeader(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if name != tt.name || value != tt.value {
+			t.Errorf("parseCustomHeader(%q) = %q, %q, want %q, %q", tt.in, name, value, tt.name, tt.value)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// IKEv2 constants from RFC 7296.
+const (
+	ikeSA        = 33
+	ikeKE        = 34
+	ikeNotify    = 41
+	ikeNonce     = 40
+	ikeSAInit    = 34
+	ikeInitiator = 0x08
+	ikeResponse  = 0x20
+	ikeDHGroup   = 14 // 2048-bit MODP
+)
+
+var ikeTransformTypes = map[uint8]string{1: "ENCR", 2: "PRF", 3: "INTEG", 4: "DH"}
+
+var ikeTransformIDs = map[[2]uint16]string{
+	{1, 3}: "3DES", {1, 12}: "AES_CBC", {1, 20}: "AES_GCM_16",
+	{2, 2}: "HMAC_SHA1", {2, 5}: "HMAC_SHA2_256", {2, 7}: "HMAC_SHA2_512",
+	{3, 2}: "HMAC_SHA1_96", {3, 12}: "HMAC_SHA2_256_128", {3, 14}: "HMAC_SHA2_512_256",
+	{4, 2}: "MODP_1024", {4, 14}: "MODP_2048", {4, 19}: "ECP_256", {4, 31}: "CURVE25519",
+}
+
+var ikeNotifyTypes = map[uint16]string{
+	14: "NO_PROPOSAL_CHOSEN", 17: "INVALID_KE_PAYLOAD", 16390: "COOKIE",
+}
+
+// ikeProbe sends an IKEv2 IKE_SA_INIT request to ip on UDP 500, then on
+// 4500 with the non-ESP marker, and reports whether a valid IKE response
+// came back. The string describes the transforms the responder chose or
+// the notification it answered with. IKE_SA_INIT is used rather than an
+// INFORMATIONAL exchange because responders silently drop INFORMATIONAL
+// messages for SAs they do not know.
+func ikeProbe(ip string, timeout time.Duration) (bool, string, error) {
+	var lastErr error
+	for _, port := range []string{"500", "4500"} {
+		ok, detail, err := ikeExchange(net.JoinHostPort(ip, port), port == "4500", timeout)
+		if ok {
+			return true, "udp/" + port + " " + detail, nil
+		}
+		lastErr = err
+	}
+	return false, "", lastErr
+}
+
+func ikeExchange(addr string, natT bool, timeout time.Duration) (bool, string, error) {
+	spi := make([]byte, 8)
+	rand.Read(spi)
+	packet := ikeSAInitRequest(spi)
+	if natT {
+		packet = append(make([]byte, 4), packet...)
+	}
+
+	conn, err := newDialer().Dial("udp", addr)
+	if err != nil {
+		return false, "", err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := conn.Write(packet); err != nil {
+		return false, "", err
+	}
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return false, "", err
+	}
+	resp := buf[:n]
+	if natT && len(resp) >= 4 && binary.BigEndian.Uint32(resp) == 0 {
+		resp = resp[4:]
+	}
+	return parseIKEResponse(resp, spi)
+}
+
+// ikeSAInitRequest builds an IKE_SA_INIT message offering AES-CBC-128,
+// HMAC-SHA2-256 and MODP 2048, the suite nearly every responder accepts.
+func ikeSAInitRequest(spi []byte) []byte {
+	transform := func(last bool, ttype uint8, id uint16, attrs []byte) []byte {
+		t := make([]byte, 8, 8+len(attrs))
+		if !last {
+			t[0] = 3
+		}
+		binary.BigEndian.PutUint16(t[2:], uint16(8+len(attrs)))
+		t[4] = ttype
+		binary.BigEndian.PutUint16(t[6:], id)
+		return append(t, attrs...)
+	}
+	keyLength := []byte{0x80, 0x0e, 0x00, 0x80} // TV attribute, key length 128
+	var transforms []byte
+	transforms = append(transforms, transform(false, 1, 12, keyLength)...)
+	transforms = append(transforms, transform(false, 2, 5, nil)...)
+	transforms = append(transforms, transform(false, 3, 12, nil)...)
+	transforms = append(transforms, transform(true, 4, ikeDHGroup, nil)...)
+
+	proposal := make([]byte, 8, 8+len(transforms))
+	binary.BigEndian.PutUint16(proposal[2:], uint16(8+len(transforms)))
+	proposal[4] = 1 // proposal number
+	proposal[5] = 1 // protocol IKE
+	proposal[7] = 4 // transforms
+	proposal = append(proposal, transforms...)
+
+	payload := func(next uint8, body []byte) []byte {
+		p := make([]byte, 4, 4+len(body))
+		p[0] = next
+		binary.BigEndian.PutUint16(p[2:], uint16(4+len(body)))
+		return append(p, body...)
+	}
+	ke := make([]byte, 4+256)
+	binary.BigEndian.PutUint16(ke, ikeDHGroup)
+	rand.Read(ke[4:])
+	ke[4] &= 0x7f // stay below the group prime
+	nonce := make([]byte, 32)
+	rand.Read(nonce)
+
+	var body []byte
+	body = append(body, payload(ikeKE, proposal)...)
+	body = append(body, payload(ikeNonce, ke)...)
+	body = append(body, payload(0, nonce)...)
+
+	header := make([]byte, 28, 28+len(body))
+	copy(header, spi)
+	header[16] = ikeSA
+	header[17] = 0x20 // version 2.0
+	header[18] = ikeSAInit
+	header[19] = ikeInitiator
+	binary.BigEndian.PutUint32(header[24:], uint32(28+len(body)))
+	return append(header, body...)
+}
+
+func parseIKEResponse(resp, spi []byte) (bool, string, error) {
+	if len(resp) < 28 || string(resp[:8]) != string(spi) || resp[18] != ikeSAInit || resp[19]&ikeResponse == 0 {
+		return false, "", errors.New("not an IKEv2 response")
+	}
+	var details []string
+	next, rest := resp[16], resp[28:]
+	for next != 0 && len(rest) >= 4 {
+		length := int(binary.BigEndian.Uint16(rest[2:]))
+		if length < 4 || length > len(rest) {
+			break
+		}
+		body := rest[4:length]
+		switch next {
+		case ikeSA:
+			details = append(details, ikeTransforms(body)...)
+		case ikeNotify:
+			if len(body) >= 4 {
+				t := binary.BigEndian.Uint16(body[2:])
+				name, ok := ikeNotifyTypes[t]
+				if !ok {
+					name = fmt.Sprintf("NOTIFY_%d", t)
+				}
+				details = append(details, name)
+			}
+		}
+		next, rest = rest[0], rest[length:]
+	}
+	return true, strings.Join(details, " "), nil
+}
+
+// ikeTransforms lists the transforms of the first proposal in an SA payload.
+func ikeTransforms(sa []byte) []string {
+	if len(sa) < 8 {
+		return nil
+	}
+	spiSize := int(sa[6])
+	rest := sa[min(8+spiSize, len(sa)):]
+	var names []string
+	for len(rest) >= 8 {
+		length := int(binary.BigEndian.Uint16(rest[2:]))
+		if length < 8 || length > len(rest) {
+			break
+		}
+		ttype, id := rest[4], binary.BigEndian.Uint16(rest[6:])
+		name, ok := ikeTransformIDs[[2]uint16{uint16(ttype), id}]
+		if !ok {
+			name = fmt.Sprintf("%d", id)
+		}
+		if length >= 12 && binary.BigEndian.Uint16(rest[8:]) == 0x800e {
+			name += fmt.Sprintf("-%d", binary.BigEndian.Uint16(rest[10:]))
+		}
+		names = append(names, ikeTransformTypes[ttype]+"_"+name)
+		if rest[0] == 0 {
+			break
+		}
+		rest = rest[length:]
+	}
+	return names
+}
+
+// checkIKE probes every address the hosts resolve to and flags the ones
+// that run an IKE responder.
+func checkIKE(hosts []string) {
+	probed := make(map[string]bool)
+	for _, host := range hosts {
+		for _, ip := range lookupIPs(host) {
+			if probed[ip.String()] {
+				continue
+			}
+			probed[ip.String()] = true
+			ok, detail, _ := ikeProbe(ip.String(), dnsQueryTimeout)
+			if !ok {
+				continue
+			}
+			if verbose {
+				fmt.Fprintf(status, " - [IKE-VPN] %s (%s) %s\n", host, ip, detail)
+			} else {
+				fmt.Fprintf(status, " - [IKE-VPN] %s (%s)\n", host, ip)
+			}
+		}
+	}
+}
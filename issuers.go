@@ -0,0 +1,90 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// IssuerAnalysis groups hosts by who issued their certificate and by the
+// key it certifies.
+type IssuerAnalysis struct {
+	ByIssuer   map[string][]string `json:"by_issuer"`
+	SharedKeys map[string][]string `json:"shared_keys,omitempty"`
+}
+
+// analyzeCertIssuers groups the hosts of certs by issuer organization and
+// common name. Hosts whose certificates certify the same public key are
+// grouped as well, a reused key is a much stronger sign of shared
+// infrastructure than a shared CA. The ACME account behind a Let's Encrypt
+// certificate is not recorded in the certificate (1.3.6.1.4.1.11129.2.4.2
+// holds the CT log SCTs), so the key is the closest available signal.
+func analyzeCertIssuers(certs map[string]*x509.Certificate) IssuerAnalysis {
+	analysis := IssuerAnalysis{
+		ByIssuer:   make(map[string][]string),
+		SharedKeys: make(map[string][]string),
+	}
+	byKey := make(map[string][]string)
+	for host, cert := range certs {
+		analysis.ByIssuer[issuerLabel(cert)] = append(analysis.ByIssuer[issuerLabel(cert)], host)
+		sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+		key := hex.EncodeToString(sum[:])
+		byKey[key] = append(byKey[key], host)
+	}
+	for key, hosts := range byKey {
+		if len(hosts) > 1 {
+			analysis.SharedKeys[key] = hosts
+		}
+	}
+	for _, group := range []map[string][]string{analysis.ByIssuer, analysis.SharedKeys} {
+		for _, hosts := range group {
+			sort.Strings(hosts)
+		}
+	}
+	return analysis
+}
+
+func issuerLabel(cert *x509.Certificate) string {
+	org := strings.Join(cert.Issuer.Organization, ", ")
+	if org == "" {
+		return cert.Issuer.CommonName
+	}
+	return org + " / " + cert.Issuer.CommonName
+}
+
+// fetchCertificate returns the leaf certificate host serves on port 443.
+func fetchCertificate(host string) (*x509.Certificate, error) {
+	chain, err := retrieveCertChain(host)
+	if err != nil {
+		return nil, err
+	}
+	return chain[0], nil
+}
+
+// checkCertIssuers fetches the certificate of every host and prints the
+// issuer groups and any keys shared between hosts.
+func checkCertIssuers(hosts []string) IssuerAnalysis {
+	certs := make(map[string]*x509.Certificate)
+	for _, host := range hosts {
+		if cert, err := fetchCertificate(host); err == nil {
+			certs[host] = cert
+		}
+	}
+	analysis := analyzeCertIssuers(certs)
+
+	issuers := make([]string, 0, len(analysis.ByIssuer))
+	for issuer := range analysis.ByIssuer {
+		issuers = append(issuers, issuer)
+	}
+	sort.Strings(issuers)
+	for _, issuer := range issuers {
+		fmt.Fprintf(status, " - %s: %s\n", issuer, strings.Join(analysis.ByIssuer[issuer], ", "))
+	}
+	for key, hosts := range analysis.SharedKeys {
+		fmt.Fprintf(status, " - [SHARED-KEY] %s... used by %s\n", key[:16], strings.Join(hosts, ", "))
+	}
+	return analysis
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// maxScripts bounds how many scripts are downloaded per host.
+const maxScripts = 50
+
+var scriptSrcPattern = regexp.MustCompile(`(?i)<script[^>]+src\s*=\s*["']([^"']+)["']`)
+
+// jsSubdomainExtract fetches the front page of host and every script it
+// loads, and returns the subdomains of domain mentioned in them. Inline
+// scripts are covered by scanning the page itself.
+func jsSubdomainExtract(host string, domain string) ([]string, error) {
+	base, err := url.Parse("https://" + host + "/")
+	if err != nil {
+		return nil, err
+	}
+	page, ok := fetchText(base.String())
+	if !ok {
+		return nil, errors.New("front page not available")
+	}
+	namePattern := subdomainPattern(domain)
+
+	seen := make(map[string]bool)
+	var result []string
+	extract := func(text string) {
+		for _, name := range matchSubdomains(namePattern, text, domain) {
+			if !seen[name] {
+				seen[name] = true
+				result = append(result, name)
+			}
+		}
+	}
+	extract(page)
+
+	fetched := make(map[string]bool)
+	for _, match := range scriptSrcPattern.FindAllStringSubmatch(page, -1) {
+		if len(fetched) == maxScripts {
+			break
+		}
+		ref, err := base.Parse(strings.TrimSpace(match[1]))
+		if err != nil || (ref.Scheme != "http" && ref.Scheme != "https") || fetched[ref.String()] {
+			continue
+		}
+		fetched[ref.String()] = true
+		if script, ok := fetchText(ref.String()); ok {
+			extract(script)
+		}
+	}
+	return result, nil
+}
+
+// subdomainPattern matches hostnames ending in domain inside free text.
+func subdomainPattern(domain string) *regexp.Regexp {
+	return regexp.MustCompile(`[a-zA-Z0-9._-]+\.` + regexp.QuoteMeta(domain))
+}
+
+// matchSubdomains returns the subdomains of domain that pattern, built by
+// subdomainPattern, finds in text.
+func matchSubdomains(pattern *regexp.Regexp, text, domain string) []string {
+	var result []string
+	for _, name := range pattern.FindAllString(text, -1) {
+		name = strings.ToLower(strings.TrimLeft(name, ".-_"))
+		if strings.HasSuffix(name, "."+domain) {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// jsEnumerate runs jsSubdomainExtract on every host and returns the
+// subdomains not seen before.
+func jsEnumerate(domain string, hosts []string) []string {
+	known := make(map[string]bool, len(hosts))
+	for _, host := range hosts {
+		known[host] = true
+	}
+
+	var result []string
+	for _, host := range hosts {
+		names, err := jsSubdomainExtract(host, domain)
+		if err != nil {
+			continue
+		}
+		for _, name := range names {
+			if !known[name] {
+				known[name] = true
+				result = append(result, name)
+				fmt.Fprintf(status, " - [JS] %s referenced by %s\n", name, host)
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// kubernetesObject is the part of a Kubernetes manifest that names the
+// hosts of an Ingress. Items holds the objects of a List, which is what
+// kubectl get -o yaml prints for several resources.
+type kubernetesObject struct {
+	Kind     string `yaml:"kind"`
+	Metadata struct {
+		Name      string `yaml:"name"`
+		Namespace string `yaml:"namespace"`
+	} `yaml:"metadata"`
+	Spec struct {
+		Rules []struct {
+			Host string `yaml:"host"`
+		} `yaml:"rules"`
+	} `yaml:"spec"`
+	Items []kubernetesObject `yaml:"items"`
+}
+
+// parseKubernetesIngress returns the subdomains of domain routed by the
+// Ingress objects in r, a YAML manifest of one or more documents or the
+// equivalent JSON. A wildcard host such as *.dev.example.com yields the
+// name below the wildcard.
+func parseKubernetesIngress(r io.Reader, domain string) ([]string, error) {
+	seen := make(map[string]bool)
+	var result []string
+	var add func(obj kubernetesObject)
+	add = func(obj kubernetesObject) {
+		for _, item := range obj.Items {
+			add(item)
+		}
+		if obj.Kind != "Ingress" {
+			return
+		}
+		ingress := obj.Metadata.Name
+		if obj.Metadata.Namespace != "" {
+			ingress = obj.Metadata.Namespace + "/" + ingress
+		}
+		for _, rule := range obj.Spec.Rules {
+			host := strings.ToLower(strings.TrimPrefix(rule.Host, "*."))
+			if !seen[host] && strings.HasSuffix(host, "."+domain) {
+				seen[host] = true
+				result = append(result, host)
+				fmt.Fprintf(status, " - [K8S-INGRESS] %s (%s)\n", host, ingress)
+			}
+		}
+	}
+
+	decoder := yaml.NewDecoder(r)
+	for {
+		var obj kubernetesObject
+		err := decoder.Decode(&obj)
+		if errors.Is(err, io.EOF) {
+			return result, nil
+		}
+		if err != nil {
+			return result, fmt.Errorf("invalid Kubernetes manifest: %w", err)
+		}
+		add(obj)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseKubernetesIngress(t *testing.T) {
+	const manifest = `apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: web
+  namespace: prod
+spec:
+  rules:
+    - host: WWW.example.com
+    - host: "*.dev.example.com"
+    - host: shop.example.net
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: api
+spec:
+  rules:
+    - host: service.example.com
+---
+apiVersion: v1
+kind: List
+items:
+  - kind: Ingress
+    metadata:
+      name: api
+    spec:
+      rules:
+        - host: api.example.com
+        - host: www.example.com
+`
+	tests := []struct {
+		name    string
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		{"yaml", manifest, []string{"www.example.com", "dev.example.com", "api.example.com"}, false},
+		{"json", `{"kind": "Ingress", "metadata": {"name": "web"}, "spec": {"rules": [{"host": "app.example.com"}, {}]}}`, []string{"app.example.com"}, false},
+		{"empty", ``, nil, false},
+		{"invalid", "kind: Ingress\nspec: [", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseKubernetesIngress(strings.NewReader(tt.input), "example.com")
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: parseKubernetesIngress error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: parseKubernetesIngress = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -2,256 +2,1672 @@ package main
 
 import (
 	"bufio"
-	"crypto/tls"
+	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"net"
+	"net/url"
 	"os"
+	"os/signal"
+	"slices"
+	"strconv"
 	"strings"
-	"time"
 	"sync"
+	"syscall"
+	"time"
+
+	"github.com/noob6t5/sub_sniaX/subsniax"
 	"golang.org/x/net/dns/dnsmessage"
+	"golang.org/x/net/idna"
 )
 
-const OpCodeQuery = 0  // package isn't working so manually added.
+const OpCodeQuery = 0 // package isn't working so manually added.
+
+// Config holds the options shared by the enumeration and analysis passes.
+type Config struct {
+	Delay   int
+	Output  *os.File
+	ZoneOut *os.File
+	BGPASN  int
+
+	HackerTarget bool
+	CertSpotter  bool
+	CrtSh        bool
+	UmbrellaKey  string
+	PasteSearch  bool
+	OTXKey       string
+	APKPath      string
+	TFStatePath  string
+	IngressFile  string
+	SMTPEnum     bool
+	SMTPVRFY     bool
+	VRFYUsers    []string
+	Adaptive     bool
+	DNSSD        bool
+	NSECWalk     bool
+	PassiveOnly  bool
+
+	SecurityHeaders      bool
+	MeasureAmplification bool
+	DNSKEY               bool
+	DANE                 bool
+	IKEProbe             bool
+	VPNProbe             bool
+	CloudMetadata        bool
+	URLhaus              bool
+	Dangling             bool
+	S3Check              bool
+	Takeover             bool
+	Fingerprints         []TakeoverFingerprint
+	FollowRedirects      bool
+	WellKnown            bool
+	JSExtract            bool
+	H2Push               bool
+	ReverseDNS           bool
+	ReverseAXFR          bool
+	AXFRPorts            []string
+	NameServers          []string
+	SNIPorts             []string
+	Wordlist             []string
+	Threads              int
+	DomainConcurrency    int
+	ScreenshotDir        string
+	PathEnum             bool
+	Probe                bool
+	PathWordlist         []string
+	DetectCDN            bool
+	DetectCloud          bool
+	NoCDN                bool
+	CertIssuers          bool
+	CertChain            bool
+	CipherEnum           bool
+	RDAP                 bool
+	CymruASN             bool
+	BlacklistCheck       bool
+	DetectWAF            bool
+	MaxRedirects         int
+	NoWildcardFilter     bool
+	Depth                int
+	Mode                 string
+
+	// ExpandedTLDs maps the sibling domains -expand-tld added to their TLD
+	ExpandedTLDs map[string]string
+	// Timer times the domains and methods of the scan in progress
+	Timer *ScanTimer
+	// Stats tallies the findings, transfers and errors of the scan
+	Stats *Stats
+}
 
 func main() {
-	delay := flag.Int("delay", 1000, "Delay between requests in milliseconds")
+	os.Exit(int(run()))
+}
+
+func run() ExitCode {
+	var cfg Config
+	flag.IntVar(&cfg.Delay, "delay", 1000, "Delay between requests in milliseconds")
 	outputFile := flag.String("o", "", "Output file to save discovered subdomains")
+	zoneFile := flag.String("zone-out", "", "Write AXFR results to this file in BIND zone format")
 	domainFile := flag.String("f", "", "File containing list of domains")
+	flag.StringVar(&openIntelSource, "openintel", "", "OpenIntel measurement dump to search: an https:// URL, or a local .parquet file or directory for offline use")
+	flag.StringVar(&openIntelKey, "openintel-key", "", "Access key sent with remote -openintel downloads")
 	singleDomain := flag.String("d", "", "Single domain to enumerate subdomains")
+	var resolvers resolverList
+	flag.Float64Var(&requestRate, "rps", 0, "Maximum DNS queries and connections per second across all domains and threads (0 for no limit)")
+	flag.Var(&resolvers, "resolver", "DNS server as host:port, repeatable for round-robin, or one DoH resolver: doh://google, cloudflare://, quad9:// or an https:// URL")
+	doh := flag.String("doh", "", "DNS-over-HTTPS endpoint URL, or google, cloudflare or quad9, that carries every lookup instead of port 53")
+	ztDNS := flag.Bool("zt-dns", false, "Send all lookups to the -resolver DoH endpoint authenticated with a client certificate")
+	ztCert := flag.String("zt-cert", "", "Client certificate (PEM) for -zt-dns")
+	ztKey := flag.String("zt-key", "", "Client private key (PEM) for -zt-dns")
+	flag.BoolVar(&verbose, "v", false, "Verbose output with additional analysis and recommendations")
+	flag.BoolVar(&jsonOutput, "json", false, "Write subdomains as newline-delimited JSON with their domain, source and addresses")
+	flag.BoolVar(&validateOutput, "validate-output", false, "Check every -json line against the embedded JSON Schema and exit with code 2 if any did not match")
+	flag.BoolVar(&resolveOutput, "resolve", false, "Look up the A and AAAA addresses of every subdomain and print them next to it")
+	flag.BoolVar(&bareOutput, "sublist3r", false, "Print bare subdomains on stdout and send progress messages to stderr")
+	ghArtifact := flag.Bool("gh-artifact", false, "Archive the output files and upload them as a GitHub Actions artifact")
+	mdns := flag.Bool("mdns", false, "Browse the local network for DNS-SD services over multicast DNS")
+	monitorInterval := flag.Duration("monitor-interval", 0, "Repeat the enumeration at this interval (e.g. 6h) and report new and removed subdomains")
+	serialInterval := flag.Duration("serial-interval", time.Minute, "How often -monitor-interval polls each nameserver's SOA serial, a change triggers an immediate zone transfer (0 disables)")
+	monitorState := flag.String("monitor-state", "sub_sniaX-state.json", "File the monitor keeps its last result set in")
+	ansibleInventory := flag.String("ansible-inventory", "", "Write discovered hosts to this file as an Ansible INI inventory grouped by service")
+	esURL := flag.String("es-url", "", "Elasticsearch URL to bulk index the discovered subdomains into")
+	expandTLD := flag.Bool("expand-tld", false, "Also enumerate the same name under other TLDs, those seen in CT logs first (e.g. example.net for example.com)")
+	maxTLDs := flag.Int("max-tlds", 5, "Maximum number of sibling TLDs -expand-tld adds per domain")
+	whois := flag.Bool("whois", false, "Look up the apex domains of the results via WHOIS in parallel and group those with the same registrant")
+	esIndex := flag.String("es-index", "sub_sniaX", "Elasticsearch index name for -es-url")
+	alertWebhook := flag.String("alert-webhook", "", "URL that receives a JSON diff whenever the monitor sees a change")
+	ctMonitor := flag.Bool("ct-monitor", false, "Stream new certificates from Certstream and report matching subdomains")
+	sniPorts := flag.String("sni-ports", "443", "Comma-separated ports to probe during SNI enumeration")
+	class := flag.String("dns-class", "INET", "Class of direct DNS queries and zone transfers: INET, CHAOS or HESIOD")
+	nsFile := flag.String("ns-file", "", "File of nameserver[:port] entries to use instead of the domain's NS records")
+	flag.IntVar(&srcPort, "src-port", 0, "Local port for zone transfer connections (0 lets the OS choose)")
+	axfrPorts := flag.String("axfr-ports", "53", "Comma-separated nameserver ports to try zone transfers on")
+	flag.BoolVar(&cfg.SecurityHeaders, "security-headers", false, "Grade the HTTP security headers of discovered hosts")
+	flag.IntVar(&cfg.BGPASN, "bgp-asn", 0, "Expected origin ASN; flag resolved IPs announced by any other AS")
+	flag.Var(headerFlag{}, "header", "Extra `Name: Value` header for HTTP probe requests (repeatable)")
+	flag.BoolVar(&cfg.MeasureAmplification, "measure-amplification", false, "Measure DNS response sizes and report the highest amplification factors")
+	flag.BoolVar(&cfg.DNSKEY, "dnskey", false, "Collect the DNSSEC keys of the domain and flag weak ones")
+	flag.BoolVar(&cfg.DANE, "dane", false, "Validate the mail servers of the domain against their DANE TLSA records")
+	flag.BoolVar(&cfg.Takeover, "takeover", false, "Flag hosts whose CNAME points at an unclaimed name on a service such as S3, Heroku or GitHub Pages")
+	fingerprintFile := flag.String("fingerprints", "", "JSON file of takeover fingerprints in the can-i-take-over-xyz format for -takeover (default: built-in list)")
+	flag.BoolVar(&cfg.S3Check, "s3-check", false, "Look for S3 buckets named after the domain, its subdomains and common words, and flag public ones")
+	flag.BoolVar(&cfg.Dangling, "dangling", false, "Flag discovered hosts whose cloud addresses look deprovisioned")
+	flag.BoolVar(&cfg.URLhaus, "urlhaus-check", false, "Look discovered hosts up in the abuse.ch URLhaus malware database")
+	flag.StringVar(&urlhausKey, "urlhaus-key", "", "abuse.ch Auth-Key for -urlhaus-check")
+	flag.BoolVar(&cfg.CloudMetadata, "cloud-metadata-check", false, "Test discovered hosts for SSRF to cloud metadata endpoints through common URL parameters")
+	flag.BoolVar(&cfg.VPNProbe, "vpn-probe", false, "Look for SSL VPN portals on ports 4433, 8443, 10443 and 4000 of discovered hosts")
+	flag.BoolVar(&cfg.IKEProbe, "ike-probe", false, "Probe discovered addresses for IPsec VPN endpoints on UDP 500 and 4500")
+	flag.BoolVar(&cfg.PassiveOnly, "passive-only", false, "Skip zone transfers, CNAME chaining and wordlist probing, only the enabled passive sources are queried")
+	flag.BoolVar(&cfg.CrtSh, "passive", false, "Query crt.sh for names on certificates in CT logs (no key needed, can be slow)")
+	flag.BoolVar(&cfg.CertSpotter, "certspotter", false, "Query the CertSpotter CT search API for certificate names (no key needed, rate limited)")
+	flag.BoolVar(&cfg.HackerTarget, "hackertarget", false, "Query the HackerTarget host search API (free tier is rate limited)")
+	flag.BoolVar(&cfg.PasteSearch, "paste-search", false, "Search Pastebin and AlienVault OTX for leaked subdomains")
+	flag.StringVar(&cfg.OTXKey, "otx-key", "", "AlienVault OTX API key for -paste-search (optional, raises the rate limit)")
+	flag.StringVar(&cfg.APKPath, "apk", "", "Extract subdomains from an Android APK or iOS IPA file")
+	flag.StringVar(&cfg.TFStatePath, "tf-state", "", "Extract subdomains from the resource attributes of a Terraform state file")
+	flag.StringVar(&cfg.IngressFile, "k8s-ingress-file", "", "Extract subdomains from the Ingress rules of a Kubernetes YAML or JSON manifest")
+	flag.BoolVar(&cfg.SMTPEnum, "smtp-enum", false, "Collect hostnames from the SMTP banners of the domain's mail servers")
+	flag.BoolVar(&cfg.SMTPVRFY, "smtp-vrfy", false, "Try VRFY and EXPN for common usernames on the domain's mail servers and flag those that answer")
+	vrfyWordlist := flag.String("smtp-vrfy-wordlist", "", "File with one username per line for -smtp-vrfy (default: built-in list)")
+	flag.BoolVar(&cfg.Adaptive, "adaptive", false, "Probe numbered and versioned variants of discovered subdomains")
+	flag.IntVar(&permuteMax, "permute-max", 20, "Highest counter tried when -adaptive expands numbered names")
+	flag.BoolVar(&cfg.DNSSD, "dns-sd", false, "Browse the DNS-SD service records published under the domain")
+	flag.BoolVar(&cfg.NSECWalk, "nsec-walk", false, "Walk the NSEC chain of a DNSSEC-signed zone on its first nameserver to list every name in it")
+	flag.StringVar(&cfg.UmbrellaKey, "umbrella-key", "", "Cisco Umbrella Investigate API key; enables the Umbrella passive source")
+	flag.BoolVar(&cfg.FollowRedirects, "follow-redirects", false, "Record the HTTP redirect chain of every discovered host")
+	flag.IntVar(&cfg.MaxRedirects, "max-redirects", 10, "Maximum redirect hops to follow per host")
+	flag.BoolVar(&cfg.WellKnown, "well-known", false, "Mine /.well-known documents (api-catalog, host-meta, security.txt) of discovered hosts")
+	flag.BoolVar(&cfg.JSExtract, "js-extract", false, "Extract subdomains from the JavaScript loaded by discovered hosts")
+	flag.BoolVar(&cfg.H2Push, "h2-push", false, "Collect hostnames from HTTP/2 server push promises of discovered hosts")
+	flag.BoolVar(&cfg.CipherEnum, "cipher-enum", false, "List the TLS 1.0-1.2 cipher suites each discovered host accepts on port 443 and flag RC4, 3DES and NULL suites")
+	flag.BoolVar(&cfg.CertChain, "cert-chain", false, "Inspect the certificate chains of discovered hosts for private CAs and internal AIA endpoints")
+	flag.BoolVar(&cfg.CertIssuers, "cert-issuers", false, "Group discovered hosts by certificate issuer and shared keys")
+	flag.BoolVar(&cfg.NoCDN, "no-cdn", false, "Mark CDN-served hosts and leave them out of the probes that follow enumeration")
+	flag.BoolVar(&cfg.DetectCloud, "cloud", false, "Name the cloud service (CloudFront, App Service, App Engine, ...) discovered hosts point at by their CNAME")
+	flag.BoolVar(&cfg.DetectCDN, "cdn", false, "Identify the CDN provider in front of discovered hosts")
+	flag.BoolVar(&cfg.CymruASN, "asn", false, "Look up the origin AS of discovered addresses via the Team Cymru DNS service")
+	flag.BoolVar(&cfg.RDAP, "rdap", false, "Look up the network owner of discovered addresses via RDAP")
+	flag.BoolVar(&cfg.DetectWAF, "waf", false, "Fingerprint web application firewalls in front of discovered hosts")
+	flag.BoolVar(&cfg.ReverseAXFR, "reverse-axfr", false, "Attempt zone transfers of the reverse zones covering discovered addresses")
+	flag.StringVar(&cfg.ScreenshotDir, "screenshot-dir", "", "Save screenshots of discovered web hosts and an index.html gallery in this directory")
+	flag.BoolVar(&cfg.BlacklistCheck, "bl-check", false, "Check discovered hosts against public malware and phishing blacklists")
+	flag.BoolVar(&cfg.Probe, "probe", false, "Request every discovered host over HTTP and HTTPS and report the status, final URL and Server header of those that answer")
+	flag.DurationVar(&liveClient.Timeout, "probe-timeout", 10*time.Second, "Time allowed for each -probe request, redirects included")
+	flag.BoolVar(&cfg.PathEnum, "path-enum", false, "Probe common paths on discovered web hosts")
+	flag.DurationVar(&tlsTimeout, "tls-timeout", 5*time.Second, "Time allowed for the connect and TLS handshake of each SNI probe")
+	flag.BoolVar(&cfg.NoWildcardFilter, "no-wildcard-filter", false, "Probe every wordlist name even when the domain has a wildcard DNS record")
+	flag.StringVar(&cfg.Mode, "mode", subsniax.ModeSNI, "How wordlist names are tested: dns (A/AAAA lookup), sni (TLS handshake) or both")
+	flag.IntVar(&cfg.Depth, "depth", 1, "Levels below the domain to enumerate, each extra level re-runs AXFR and SNI under every name found on the one above")
+	flag.IntVar(&cfg.Threads, "threads", 20, "Number of names probed at once during SNI enumeration and of DNS pipeline workers")
+	flag.IntVar(&cfg.DomainConcurrency, "domain-concurrency", 10, "Number of domains enumerated at once")
+	wordlist := flag.String("w", "", "File with one subdomain label per line for SNI enumeration (default: built-in list)")
+	pathWordlist := flag.String("path-wordlist", "", "File with one path per line for -path-enum (default: built-in list)")
+	flag.BoolVar(&cfg.ReverseDNS, "reverse-dns", false, "Run PTR lookups on the addresses of discovered hosts")
+	profile := flag.String("profile", "", "Preset for the other flags: fast, balanced, thorough (every method, NSEC walking included) or stealth; flags given explicitly still win")
 	flag.Parse()
 
-	domains := loadDomains(*domainFile, *singleDomain)
-	if len(domains) == 0 {
-		fmt.Println("Usage: sub_sniaX -f <domain_file> or -d <single_domain> [-delay <ms>] [-o <output>]")
-		os.Exit(1)
+	if *profile != "" {
+		if err := applyProfile(*profile, &cfg); err != nil {
+			log.Println(err)
+			return ExitConfigError
+		}
 	}
 
-	var output *os.File
-	if *outputFile != "" {
-		var err error
-		output, err = os.Create(*outputFile)
+	if bareOutput || jsonOutput {
+		status = os.Stderr
+	}
+	if validateOutput && !jsonOutput {
+		log.Println("-validate-output needs -json")
+		return ExitConfigError
+	}
+	resolveWorkers = max(cfg.Threads, 1)
+	cloudOutput = cfg.DetectCloud
+	cdnOutput = cfg.DetectCDN
+	ports, err := parsePorts(*axfrPorts)
+	if err != nil {
+		log.Printf("Invalid -axfr-ports: %v\n", err)
+		return ExitConfigError
+	}
+	cfg.AXFRPorts = ports
+	cfg.SNIPorts, err = parsePorts(*sniPorts)
+	if err != nil {
+		log.Printf("Invalid -sni-ports: %v\n", err)
+		return ExitConfigError
+	}
+	if _, ok := bruteModes[cfg.Mode]; !ok {
+		log.Printf("Invalid -mode %q, use dns, sni or both\n", cfg.Mode)
+		return ExitConfigError
+	}
+	if cfg.DomainConcurrency < 1 {
+		log.Printf("Invalid -domain-concurrency %d, it must be at least 1\n", cfg.DomainConcurrency)
+		return ExitConfigError
+	}
+	if srcPort < 0 || srcPort > 65535 {
+		log.Printf("Invalid -src-port %d\n", srcPort)
+		return ExitConfigError
+	}
+	dnsClass, err = parseDNSClass(*class)
+	if err != nil {
+		log.Println(err)
+		return ExitConfigError
+	}
+	if *nsFile != "" {
+		cfg.NameServers, err = loadNameservers(*nsFile)
+		if err != nil {
+			log.Printf("Failed to read nameserver file: %v\n", err)
+			return ExitConfigError
+		}
+	}
+	cfg.Wordlist = subsniax.DefaultWordlist
+	if *wordlist != "" {
+		cfg.Wordlist, err = loadLabels(*wordlist)
+		if err != nil {
+			log.Printf("Failed to read wordlist: %v\n", err)
+			return ExitConfigError
+		}
+	}
+	cfg.PathWordlist = defaultPaths
+	if *pathWordlist != "" {
+		cfg.PathWordlist, err = loadWordlist(*pathWordlist)
 		if err != nil {
-			log.Fatalf("Failed to create output file: %v\n", err)
+			log.Printf("Failed to read path wordlist: %v\n", err)
+			return ExitConfigError
 		}
-		defer output.Close()
 	}
 
-	var wg sync.WaitGroup
-	for _, domain := range domains {
-		wg.Add(1)
-		go func(domain string) {
-			defer wg.Done()
-			// Normalize domain before processing
-			normalizedDomain := normalizeDomain(domain)
-			fmt.Printf("\nEnumerating subdomains for %s...\n\n", normalizedDomain)
-			enumerateSubdomains(normalizedDomain, *delay, output)
-		}(domain)
+	cfg.Fingerprints = takeoverFingerprints
+	if *fingerprintFile != "" {
+		cfg.Fingerprints, err = loadFingerprints(*fingerprintFile)
+		if err != nil {
+			log.Printf("Failed to load fingerprints: %v\n", err)
+			return ExitConfigError
+		}
+	}
+	cfg.VRFYUsers = defaultVRFYUsers
+	if *vrfyWordlist != "" {
+		cfg.VRFYUsers, err = loadLabels(*vrfyWordlist)
+		if err != nil {
+			log.Printf("Failed to read VRFY wordlist: %v\n", err)
+			return ExitConfigError
+		}
 	}
-	wg.Wait()
-}
 
-func loadDomains(domainFile, singleDomain string) []string {
-	var domains []string
-	if domainFile != "" {
-		file, err := os.Open(domainFile)
+	domains, err := loadDomains(*domainFile, *singleDomain)
+	if err != nil {
+		log.Println(err)
+		return ExitConfigError
+	}
+	if len(domains) == 0 && !*mdns {
+		fmt.Println("Usage: sub_sniaX -f <domain_file> or -d <single_domain> or domains on stdin [-delay <ms>] [-o <output>]")
+		return ExitConfigError
+	}
+
+	if *doh != "" {
+		if len(resolvers) > 0 {
+			log.Println("-doh cannot be combined with -resolver")
+			return ExitConfigError
+		}
+		endpoint := *doh
+		if _, ok := resolverPresets[endpoint]; ok {
+			endpoint += "://"
+		}
+		if _, err := dohEndpoint(endpoint); err != nil {
+			log.Printf("Invalid -doh: %v\n", err)
+			return ExitConfigError
+		}
+		resolvers = resolverList{endpoint}
+	}
+	switch {
+	case *ztDNS:
+		if len(resolvers) != 1 || *ztCert == "" || *ztKey == "" {
+			log.Println("-zt-dns needs -doh or -resolver with a DoH endpoint plus -zt-cert and -zt-key")
+			return ExitConfigError
+		}
+		endpoint, err := dohEndpoint(resolvers[0])
+		if err == nil {
+			resolver, err = newZeroTrustResolver(endpoint, *ztCert, *ztKey)
+		}
 		if err != nil {
-			log.Fatalf("Failed to open domain file: %v\n", err)
+			log.Printf("Failed to configure Zero Trust DNS: %v\n", err)
+			return ExitConfigError
 		}
-		defer file.Close()
+	case len(resolvers) > 0:
+		resolver, resolverServers, err = parseResolvers(resolvers)
+		if err != nil {
+			log.Printf("Failed to configure resolver: %v\n", err)
+			return ExitConfigError
+		}
+	}
+	if requestRate < 0 {
+		log.Println("-rps must not be negative")
+		return ExitConfigError
+	}
+	if requestRate > 0 {
+		paceRequests(requestRate)
+	}
+
+	if *outputFile != "" {
+		cfg.Output, err = os.Create(*outputFile)
+		if err != nil {
+			log.Printf("Failed to create output file: %v\n", err)
+			return ExitConfigError
+		}
+		defer cfg.Output.Close()
+	}
+	if *zoneFile != "" {
+		cfg.ZoneOut, err = os.Create(*zoneFile)
+		if err != nil {
+			log.Printf("Failed to create zone file: %v\n", err)
+			return ExitConfigError
+		}
+		defer cfg.ZoneOut.Close()
+	}
+
+	// The first SIGINT or SIGTERM cancels the scan and keeps what was found
+	// so far, a second one kills the process
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	context.AfterFunc(ctx, stop)
+
+	if cfg.APKPath != "" {
+		log.Println("Only analyze apps you are authorized to reverse engineer, app store terms and local law may forbid it")
+	}
+	if cfg.TFStatePath != "" {
+		log.Println("Terraform state files hold credentials and private keys in plain text, keep the file and this scan's output out of shared storage")
+	}
 
-		scanner := bufio.NewScanner(file)
-		for scanner.Scan() {
-			domain := strings.TrimSpace(scanner.Text())
-			if domain != "" {
-				domains = append(domains, domain)
+	if *mdns {
+		fmt.Fprintf(status, "\nBrowsing mDNS services on the local network...\n")
+		hosts := mdnsEnumerate()
+		writeOutput(newFindings(hosts, "mdns"), cfg.Output, nil)
+		if len(domains) == 0 {
+			if len(hosts) == 0 {
+				return ExitNoResults
 			}
+			return ExitSuccess
 		}
-		if err := scanner.Err(); err != nil {
-			log.Fatalf("Failed to read domain file: %v\n", err)
+	}
+
+	if *ctMonitor {
+		var targets []string
+		for _, domain := range domains {
+			targets = append(targets, normalizeDomain(domain))
 		}
-	} else if singleDomain != "" {
-		domains = append(domains, singleDomain)
+		fmt.Fprintf(status, "\nMonitoring Certstream for %s...\n\n", strings.Join(targets, ", "))
+		monitorCertstream(ctx, targets, cfg.Output)
+		return ExitSuccess
 	}
-	return domains
-}
 
-func normalizeDomain(domain string) string {
-	// Remove protocols (http://, https://)
-	if strings.HasPrefix(domain, "https://") {
-		domain = strings.TrimPrefix(domain, "https://")
-	} else if strings.HasPrefix(domain, "http://") {
-		domain = strings.TrimPrefix(domain, "http://")
+	if *monitorInterval > 0 {
+		return monitorDomains(ctx, domains, &cfg, *monitorInterval, *serialInterval, *monitorState, *alertWebhook)
+	}
+
+	if *expandTLD {
+		domains, cfg.ExpandedTLDs = expandTLDs(ctx, domains, *maxTLDs)
+	}
+
+	found, failed := scanDomains(ctx, domains, &cfg)
+	var results int
+	for _, subdomains := range found {
+		results += len(subdomains)
+	}
+	if ctx.Err() != nil {
+		fmt.Fprintf(status, "\nInterrupted, found %d subdomains for %d of %d domains before stopping\n", results, len(found), len(domains))
+		if results == 0 {
+			return ExitNoResults
+		}
+		return ExitPartialSuccess
 	}
 
-	// Remove 'www.' if present
-	if strings.HasPrefix(domain, "www.") {
-		domain = strings.TrimPrefix(domain, "www.")
+	if *ansibleInventory != "" {
+		var records []DiscoveryRecord
+		for _, subdomains := range found {
+			records = append(records, addressRecords(subdomains)...)
+		}
+		classifyServices(records)
+		if err := writeAnsibleInventory(records, *ansibleInventory); err != nil {
+			log.Printf("Failed to write Ansible inventory: %v\n", err)
+		}
 	}
 
-	return domain
+	if *whois {
+		var names []string
+		for domain, subdomains := range found {
+			names = append(append(names, domain), subdomains...)
+		}
+		fmt.Fprintf(status, "\nLooking up WHOIS for the apex domains...\n")
+		checkWHOIS(apexDomains(names), cfg.Output)
+	}
+
+	if *esURL != "" {
+		var records []DiscoveryRecord
+		for _, subdomains := range found {
+			records = append(records, addressRecords(subdomains)...)
+		}
+		if err := IndexToElasticsearch(*esURL, *esIndex, records); err != nil {
+			log.Printf("Failed to index results in Elasticsearch: %v\n", err)
+		} else {
+			fmt.Fprintf(status, "\nIndexed %d records into %s\n", len(records), strings.ToLower(*esIndex))
+		}
+	}
+
+	if *ghArtifact {
+		publishArtifact(*outputFile, *zoneFile)
+	}
+	if n := invalidLines.Load(); n > 0 {
+		log.Printf("%d -json lines did not match the output schema and were left out\n", n)
+		return ExitConfigError
+	}
+	return exitCodeFor(len(domains), len(failed), results)
 }
 
-func enumerateSubdomains(domain string, delay int, output *os.File) {
-	nameServers, err := net.LookupNS(domain)
-	if err != nil {
-		log.Printf("Failed to get NS records for domain %s: %v\n", domain, err)
-		return
+// scanDomains enumerates every domain concurrently. It returns the
+// subdomains found per normalized domain and the set of normalized domains
+// that failed. Once ctx is cancelled the domains return what they found so
+// far, which is kept rather than counted as a failure.
+func scanDomains(ctx context.Context, domains []string, cfg *Config) (map[string][]string, map[string]bool) {
+	cfg.Timer = newScanTimer()
+	cfg.Stats = newStats()
+	defer cfg.Stats.report(cfg.Timer.StartTime)
+	defer cfg.Timer.report(cfg.Output)
+	var mu sync.Mutex
+	failed := make(map[string]bool)
+	results := make(map[string][]string)
+	scan := func(domain string) {
+		// Normalize domain before processing
+		normalizedDomain := normalizeDomain(domain)
+		fmt.Fprintf(status, "\nEnumerating subdomains for %s...\n\n", normalizedDomain)
+		done := cfg.Timer.startDomain(normalizedDomain)
+		cfg.Stats.startDomain(normalizedDomain)
+		found, err := enumerateSubdomains(ctx, normalizedDomain, cfg)
+		done()
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil && ctx.Err() == nil {
+			failed[normalizedDomain] = true
+			return
+		}
+		if len(found) > 0 {
+			results[normalizedDomain] = found
+		}
 	}
 
+	// A fixed number of workers keeps a long domain list from starting
+	// every enumeration, and all of their connections, at once
+	jobs := make(chan string)
 	var wg sync.WaitGroup
-	for _, ns := range nameServers {
+	for range min(max(cfg.DomainConcurrency, 1), len(domains)) {
 		wg.Add(1)
-		go func(nsHost string) {
+		go func() {
 			defer wg.Done()
-			fmt.Printf("Attempting AXFR on %-35s", domain+" via "+nsHost)
-			subdomains := attemptAXFR(domain, nsHost, delay)
-			if len(subdomains) == 0 {
-				fmt.Println("AXFR failed or timed out.")
+			for domain := range jobs {
+				scan(domain)
 			}
-			writeOutput(subdomains, output)
-		}(ns.Host)
+		}()
+	}
+feed:
+	for _, domain := range domains {
+		select {
+		case jobs <- domain:
+		case <-ctx.Done():
+			break feed
+		}
 	}
+	close(jobs)
 	wg.Wait()
+	return results, failed
+}
 
-	// Optimizing CNAME chaining with batch DNS query
-	fmt.Printf("\nAttempting CNAME chaining for %s...\n", domain)
-	cnameChained := cnameChain(domain)
-	writeOutput(cnameChained, output)
+// publishArtifact archives the output files that were written and uploads
+// the archive to the GitHub Actions artifact store.
+func publishArtifact(paths ...string) {
+	var files []string
+	for _, path := range paths {
+		if path != "" {
+			files = append(files, path)
+		}
+	}
+	if len(files) == 0 {
+		log.Println("No output files to upload, use -o or -zone-out with -gh-artifact")
+		return
+	}
+	if err := writeResultsArchive(resultsArchive, files); err != nil {
+		log.Printf("Failed to write %s: %v\n", resultsArchive, err)
+		return
+	}
+	if err := uploadArtifact(artifactName, resultsArchive); err != nil {
+		log.Printf("Failed to upload artifact: %v\n", err)
+		return
+	}
+	fmt.Fprintf(status, "\nUploaded %s as artifact %s\n", resultsArchive, artifactName)
+}
 
-	// SNI enumeration in parallel
-	fmt.Printf("\nAttempting SNI enumeration for %s...\n", domain)
-	sniSubdomains := sniEnumerate(domain, delay)
-	writeOutput(sniSubdomains, output)
+// parsePorts splits a comma-separated port list and checks every entry.
+func parsePorts(list string) ([]string, error) {
+	var ports []string
+	for _, port := range strings.Split(list, ",") {
+		port = strings.TrimSpace(port)
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return nil, fmt.Errorf("%q is not a port number", port)
+		}
+		ports = append(ports, port)
+	}
+	return ports, nil
 }
 
-func attemptAXFR(domain, ns string, delay int) []string {
-	var result []string
-	conn, err := net.Dial("tcp", ns+":53")
+// loadNameservers reads one nameserver per line, as host or host:port.
+func loadNameservers(path string) ([]string, error) {
+	file, err := os.Open(path)
 	if err != nil {
-		log.Printf("Failed to connect to %s for AXFR: %v\n", ns, err)
-		return result
+		return nil, err
 	}
-	defer conn.Close()
+	defer file.Close()
 
-	msg := dnsmessage.Message{
-		Header: dnsmessage.Header{
-			RecursionDesired: true,
-			Response:         false,
-			OpCode:           OpCodeQuery,
-		},
-		Questions: []dnsmessage.Question{
-			{
-				Name:  dnsmessage.MustNewName(domain + "."),
-				Type:  dnsmessage.TypeAXFR,
-				Class: dnsmessage.ClassINET,
-			},
-		},
+	var servers []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if _, port, err := net.SplitHostPort(line); err == nil {
+			if _, err := parsePorts(port); err != nil {
+				return nil, fmt.Errorf("%s: %w", line, err)
+			}
+		}
+		servers = append(servers, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("%s lists no nameservers", path)
 	}
+	return servers, nil
+}
 
-	buf, err := msg.Pack()
+// loadLabels reads one subdomain label per line, dropping duplicates.
+func loadLabels(path string) ([]string, error) {
+	file, err := os.Open(path)
 	if err != nil {
-		log.Printf("Failed to pack AXFR request: %v\n", err)
-		return result
+		return nil, err
 	}
+	defer file.Close()
 
-	for attempts := 0; attempts < 3; attempts++ {
-		_, err = conn.Write(buf)
-		if err != nil {
-			log.Printf("Failed to send AXFR request: %v\n", err)
-			return result
+	var labels []string
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || seen[line] {
+			continue
 		}
+		seen[line] = true
+		labels = append(labels, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(labels) == 0 {
+		return nil, fmt.Errorf("%s lists no subdomains", path)
+	}
+	return labels, nil
+}
 
-		conn.SetReadDeadline(time.Now().Add(time.Duration(delay) * time.Millisecond))
-		resBuf := make([]byte, 512)
-		n, err := conn.Read(resBuf)
+// loadDomains returns the targets from domainFile, singleDomain or, when
+// neither is given and stdin is not a terminal, from stdin, one per line.
+// Each target is normalized and listed once, in the order first seen.
+func loadDomains(domainFile, singleDomain string) ([]string, error) {
+	switch {
+	case domainFile != "":
+		file, err := os.Open(domainFile)
 		if err != nil {
-			log.Println("Error reading AXFR response or AXFR complete:", err)
-			time.Sleep(2 * time.Second)
+			return nil, fmt.Errorf("failed to open domain file: %w", err)
+		}
+		defer file.Close()
+		domains, err := readDomains(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read domain file: %w", err)
+		}
+		return uniqueDomains(domains), nil
+	case singleDomain != "":
+		return uniqueDomains([]string{singleDomain}), nil
+	}
+
+	info, err := os.Stdin.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice != 0 {
+		// Interactive, nothing is being piped in
+		return nil, nil
+	}
+	domains, err := readDomains(os.Stdin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read domains from stdin: %w", err)
+	}
+	return uniqueDomains(domains), nil
+}
+
+// uniqueDomains normalizes domains and drops the ones that normalize to a
+// target already listed or to nothing at all.
+func uniqueDomains(domains []string) []string {
+	seen := make(map[string]bool, len(domains))
+	var unique []string
+	for _, domain := range domains {
+		domain = normalizeDomain(domain)
+		if domain == "" || seen[domain] {
 			continue
 		}
+		seen[domain] = true
+		unique = append(unique, domain)
+	}
+	return unique
+}
+
+// readDomains reads one domain per line, skipping blank lines.
+func readDomains(r io.Reader) ([]string, error) {
+	var domains []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		domain := strings.TrimSpace(scanner.Text())
+		if domain != "" {
+			domains = append(domains, domain)
+		}
+	}
+	return domains, scanner.Err()
+}
+
+// normalizeDomain reduces a target as users paste it, a bare name or a URL
+// with port, path and query, to its lowercase hostname without a leading
+// www. or trailing dots. Internationalized names are converted to punycode.
+func normalizeDomain(domain string) string {
+	domain = strings.TrimSpace(domain)
+	if !strings.Contains(domain, "://") {
+		domain = "http://" + domain
+	}
+	var host string
+	if u, err := url.Parse(domain); err == nil {
+		host = u.Hostname()
+	} else {
+		// Not a valid URL, keep what lies between the scheme and the path
+		host = domain[strings.Index(domain, "://")+3:]
+		if i := strings.IndexAny(host, "/?#"); i >= 0 {
+			host = host[:i]
+		}
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+	}
+
+	host = strings.TrimRight(strings.ToLower(host), ".")
+	host = strings.TrimPrefix(host, "www.")
+	if ascii, err := idna.Lookup.ToASCII(host); err == nil {
+		host = ascii
+	}
+	return host
+}
 
-		var resp dnsmessage.Message
-		err = resp.Unpack(resBuf[:n])
+func enumerateSubdomains(ctx context.Context, domain string, cfg *Config) ([]string, error) {
+	seen := newSubdomainSet(domain)
+	seen.tld = cfg.ExpandedTLDs[domain]
+	seen.stats = cfg.Stats
+	nameServers := cfg.NameServers
+	if nameServers == nil {
+		records, err := resolver.LookupNS(ctx, domain)
 		if err != nil {
-			log.Printf("Failed to unpack AXFR response: %v\n", err)
-			break
+			log.Printf("Failed to get NS records for domain %s: %v\n", domain, err)
+			cfg.Stats.failed(domain)
+			return nil, err
+		}
+		for _, ns := range records {
+			nameServers = append(nameServers, ns.Host)
 		}
+	}
+
+	var found []string
+	if !cfg.PassiveOnly {
+		found = activeEnumerate(ctx, domain, nameServers, cfg, seen)
+	}
+	if ctx.Err() != nil {
+		return unique(found), ctx.Err()
+	}
+
+	if cfg.DNSSD {
+		stop := cfg.Timer.start(domain, "dns-sd")
+		fmt.Fprintf(status, "\nBrowsing DNS-SD services for %s...\n", domain)
+		services := enumerateDNSSD(domain)
+		writeOutput(newFindings(services, "dns-sd"), cfg.Output, seen)
+		found = append(found, services...)
+		stop()
+	}
 
-		for _, answer := range resp.Answers {
-			if answer.Header.Type == dnsmessage.TypeA || answer.Header.Type == dnsmessage.TypeCNAME {
-				subdomain := strings.TrimSuffix(answer.Header.Name.String(), ".")
-				result = append(result, subdomain)
-				fmt.Println(" -", subdomain)
+	if cfg.NSECWalk && !cfg.PassiveOnly && len(nameServers) > 0 {
+		stop := cfg.Timer.start(domain, "nsec-walk")
+		fmt.Fprintf(status, "\nWalking the NSEC chain of %s on %s...\n", domain, nameServers[0])
+		walked, err := walkNSEC(domain, nameServers[0])
+		if err != nil {
+			log.Printf("NSEC walk: %v\n", err)
+		}
+		writeOutput(newFindings(walked, "nsec"), cfg.Output, seen)
+		found = append(found, walked...)
+		stop()
+	}
+
+	if cfg.Adaptive {
+		stop := cfg.Timer.start(domain, "adaptive")
+		fmt.Fprintf(status, "\nProbing pattern variants for %s...\n", domain)
+		adaptive := adaptiveEnumerate(domain, found)
+		writeOutput(newFindings(adaptive, "adaptive"), cfg.Output, seen)
+		found = append(found, adaptive...)
+		stop()
+	}
+
+	if openIntelSource != "" {
+		stop := cfg.Timer.start(domain, "openintel")
+		fmt.Fprintf(status, "\nSearching OpenIntel measurements for %s...\n", domain)
+		measured, err := queryOpenIntel(domain)
+		if err != nil {
+			log.Printf("OpenIntel lookup for %s failed: %v\n", domain, err)
+		}
+		writeOutput(newFindings(measured, "openintel"), cfg.Output, seen)
+		found = append(found, measured...)
+		stop()
+	}
+
+	if cfg.HackerTarget {
+		stop := cfg.Timer.start(domain, "hackertarget")
+		fmt.Fprintf(status, "\nQuerying HackerTarget for %s...\n", domain)
+		passive, err := queryHackerTarget(domain)
+		if errors.Is(err, errHackerTargetLimit) {
+			log.Println("HackerTarget daily API limit reached, skipping")
+		} else if err != nil {
+			log.Printf("HackerTarget lookup for %s failed: %v\n", domain, err)
+			cfg.Stats.failed(domain)
+		}
+		writeOutput(newFindings(passive, "hackertarget"), cfg.Output, seen)
+		found = append(found, passive...)
+		stop()
+	}
+	if cfg.CrtSh {
+		stop := cfg.Timer.start(domain, "crtsh")
+		fmt.Fprintf(status, "\nQuerying crt.sh for %s...\n", domain)
+		passive, err := queryCrtSh(domain)
+		if err != nil {
+			log.Printf("crt.sh lookup for %s failed, continuing without it: %v\n", domain, err)
+			cfg.Stats.failed(domain)
+		}
+		writeOutput(newFindings(passive, "crtsh"), cfg.Output, seen)
+		found = append(found, passive...)
+		stop()
+	}
+	if cfg.CertSpotter {
+		stop := cfg.Timer.start(domain, "certspotter")
+		fmt.Fprintf(status, "\nQuerying CertSpotter for %s...\n", domain)
+		passive, err := queryCertSpotter(domain)
+		if err != nil {
+			log.Printf("CertSpotter lookup for %s incomplete: %v\n", domain, err)
+			cfg.Stats.failed(domain)
+		}
+		writeOutput(newFindings(passive, "certspotter"), cfg.Output, seen)
+		found = append(found, passive...)
+		stop()
+	}
+	if cfg.UmbrellaKey != "" {
+		stop := cfg.Timer.start(domain, "umbrella")
+		fmt.Fprintf(status, "\nQuerying Umbrella Investigate for %s...\n", domain)
+		passive, err := queryUmbrella(domain, cfg.UmbrellaKey)
+		if err != nil {
+			log.Printf("Umbrella lookup for %s failed: %v\n", domain, err)
+			cfg.Stats.failed(domain)
+		}
+		writeOutput(newFindings(passive, "umbrella"), cfg.Output, seen)
+		found = append(found, passive...)
+		stop()
+	}
+	if cfg.APKPath != "" {
+		stop := cfg.Timer.start(domain, "apk")
+		fmt.Fprintf(status, "\nExtracting subdomains from %s for %s...\n", cfg.APKPath, domain)
+		extracted, err := extractDomainsFromAPK(cfg.APKPath, domain)
+		if err != nil {
+			log.Println(err)
+		}
+		writeOutput(newFindings(extracted, "apk"), cfg.Output, seen)
+		found = append(found, extracted...)
+		stop()
+	}
+	if cfg.TFStatePath != "" {
+		stop := cfg.Timer.start(domain, "tf-state")
+		fmt.Fprintf(status, "\nExtracting subdomains from %s for %s...\n", cfg.TFStatePath, domain)
+		if file, err := os.Open(cfg.TFStatePath); err != nil {
+			log.Printf("Failed to open Terraform state: %v\n", err)
+		} else {
+			extracted, err := parseTerraformState(file, domain)
+			file.Close()
+			if err != nil {
+				log.Printf("%s: %v\n", cfg.TFStatePath, err)
+			}
+			writeOutput(newFindings(extracted, "tf-state"), cfg.Output, seen)
+			found = append(found, extracted...)
+		}
+		stop()
+	}
+	if cfg.IngressFile != "" {
+		stop := cfg.Timer.start(domain, "k8s-ingress")
+		fmt.Fprintf(status, "\nReading Ingress hosts from %s for %s...\n", cfg.IngressFile, domain)
+		if file, err := os.Open(cfg.IngressFile); err != nil {
+			log.Printf("Failed to open Kubernetes manifest: %v\n", err)
+		} else {
+			hosts, err := parseKubernetesIngress(file, domain)
+			file.Close()
+			if err != nil {
+				log.Printf("%s: %v\n", cfg.IngressFile, err)
+			}
+			writeOutput(newFindings(hosts, "k8s-ingress"), cfg.Output, seen)
+			found = append(found, hosts...)
+		}
+		stop()
+	}
+	if cfg.SMTPEnum {
+		stop := cfg.Timer.start(domain, "smtp-enum")
+		fmt.Fprintf(status, "\nReading SMTP banners for %s...\n", domain)
+		banners := smtpEnumerate(domain)
+		writeOutput(newFindings(banners, "smtp"), cfg.Output, seen)
+		found = append(found, banners...)
+		stop()
+	}
+	if cfg.PasteSearch {
+		stop := cfg.Timer.start(domain, "paste-search")
+		fmt.Fprintf(status, "\nSearching paste sites for %s...\n", domain)
+		passive, err := queryPasteSites(domain, map[string]string{"otx": cfg.OTXKey})
+		if err != nil {
+			log.Printf("Paste site search for %s incomplete: %v\n", domain, err)
+			cfg.Stats.failed(domain)
+		}
+		writeOutput(newFindings(passive, "paste"), cfg.Output, seen)
+		found = append(found, passive...)
+		stop()
+	}
+
+	if cfg.Depth > 1 && !cfg.PassiveOnly {
+		stop := cfg.Timer.start(domain, "nested")
+		fmt.Fprintf(status, "\nEnumerating below the subdomains of %s up to depth %d...\n", domain, cfg.Depth)
+		found = append(found, enumerateNested(ctx, domain, found, nameServers, cfg, seen)...)
+		stop()
+	}
+	if ctx.Err() != nil {
+		return unique(found), ctx.Err()
+	}
+
+	// Delegated subzones are served by their own nameservers, so the
+	// parent's AXFR never contains their records
+	if !cfg.PassiveOnly {
+		stop := cfg.Timer.start(domain, "delegated-axfr")
+		transferDelegatedZones(ctx, domain, found, cfg, seen)
+		stop()
+	}
+
+	hosts := unique(found)
+	if ctx.Err() != nil {
+		return hosts, ctx.Err()
+	}
+	// probeHosts are the hosts the HTTP and TLS probes reach. -no-cdn only
+	// narrows this list, CDN-served names stay in the results
+	probeHosts := slices.Clip(hosts)
+	if cfg.NoCDN {
+		stop := cfg.Timer.start(domain, "cdn-filter")
+		fmt.Fprintf(status, "\nExcluding CDN edge hosts of %s from probing...\n", domain)
+		probeHosts = excludeCDN(hosts)
+		stop()
+	}
+	if cfg.BGPASN != 0 {
+		stop := cfg.Timer.start(domain, "bgp")
+		fmt.Fprintf(status, "\nValidating BGP origins for %s against AS%d...\n", domain, cfg.BGPASN)
+		checkBGPRoutes(hosts, cfg.BGPASN)
+		stop()
+	}
+	if cfg.DNSKEY {
+		stop := cfg.Timer.start(domain, "dnskey")
+		fmt.Fprintf(status, "\nCollecting DNSKEY records for %s...\n", domain)
+		checkDNSKEY(domain, cfg.Output)
+		stop()
+	}
+	if cfg.DANE {
+		stop := cfg.Timer.start(domain, "dane")
+		fmt.Fprintf(status, "\nValidating DANE for the mail servers of %s...\n", domain)
+		checkDANE(domain)
+		stop()
+	}
+	if cfg.SMTPVRFY {
+		stop := cfg.Timer.start(domain, "smtp-vrfy")
+		fmt.Fprintf(status, "\nTrying VRFY and EXPN on the mail servers of %s...\n", domain)
+		checkSMTPVRFY(domain, cfg.VRFYUsers)
+		stop()
+	}
+	if cfg.CertIssuers {
+		stop := cfg.Timer.start(domain, "cert-issuers")
+		fmt.Fprintf(status, "\nGrouping certificates by issuer for %s...\n", domain)
+		checkCertIssuers(probeHosts)
+		stop()
+	}
+	if cfg.CertChain {
+		stop := cfg.Timer.start(domain, "cert-chain")
+		fmt.Fprintf(status, "\nInspecting certificate chains for %s...\n", domain)
+		checkCertChains(probeHosts)
+		stop()
+	}
+	if cfg.CipherEnum {
+		stop := cfg.Timer.start(domain, "cipher-enum")
+		fmt.Fprintf(status, "\nEnumerating cipher suites for %s...\n", domain)
+		checkCiphers(probeHosts)
+		stop()
+	}
+	if cfg.IKEProbe {
+		stop := cfg.Timer.start(domain, "ike-probe")
+		fmt.Fprintf(status, "\nProbing for IKE VPN endpoints for %s...\n", domain)
+		checkIKE(probeHosts)
+		stop()
+	}
+	if cfg.CloudMetadata {
+		stop := cfg.Timer.start(domain, "cloud-metadata-check")
+		fmt.Fprintf(status, "\nChecking for cloud metadata SSRF for %s...\n", domain)
+		checkCloudMetadata(probeHosts)
+		stop()
+	}
+	if cfg.VPNProbe {
+		stop := cfg.Timer.start(domain, "vpn-probe")
+		fmt.Fprintf(status, "\nProbing for SSL VPN portals for %s...\n", domain)
+		checkSSLVPN(probeHosts)
+		stop()
+	}
+	if cfg.Dangling {
+		stop := cfg.Timer.start(domain, "dangling")
+		fmt.Fprintf(status, "\nChecking for dangling cloud records for %s...\n", domain)
+		checkDangling(hosts)
+		stop()
+	}
+	if cfg.Takeover {
+		stop := cfg.Timer.start(domain, "takeover")
+		fmt.Fprintf(status, "\nChecking CNAME targets of %s for takeovers...\n", domain)
+		checkTakeovers(hosts, cfg.Fingerprints)
+		stop()
+	}
+	if cfg.S3Check {
+		stop := cfg.Timer.start(domain, "s3-check")
+		fmt.Fprintf(status, "\nLooking for S3 buckets of %s...\n", domain)
+		checkS3Buckets(domain, hosts)
+		stop()
+	}
+	if cfg.DetectCDN {
+		stop := cfg.Timer.start(domain, "cdn")
+		fmt.Fprintf(status, "\nDetecting CDN providers for %s...\n", domain)
+		checkCDN(hosts)
+		stop()
+	}
+	if cfg.DetectCloud {
+		stop := cfg.Timer.start(domain, "cloud")
+		fmt.Fprintf(status, "\nDetecting cloud providers for %s...\n", domain)
+		checkCloudProviders(hosts)
+		stop()
+	}
+	if cfg.RDAP {
+		stop := cfg.Timer.start(domain, "rdap")
+		fmt.Fprintf(status, "\nLooking up RDAP registrations for %s...\n", domain)
+		checkRDAP(hosts, cfg.Output)
+		stop()
+	}
+	if cfg.CymruASN {
+		stop := cfg.Timer.start(domain, "asn")
+		fmt.Fprintf(status, "\nLooking up origin ASNs for %s...\n", domain)
+		checkCymruASN(hosts)
+		stop()
+	}
+	if cfg.DetectWAF {
+		stop := cfg.Timer.start(domain, "waf")
+		fmt.Fprintf(status, "\nDetecting WAFs for %s...\n", domain)
+		checkWAF(probeHosts, cfg.Output)
+		stop()
+	}
+	if cfg.BlacklistCheck {
+		stop := cfg.Timer.start(domain, "bl-check")
+		fmt.Fprintf(status, "\nChecking blacklists for %s...\n", domain)
+		checkAllBlacklists(hosts)
+		stop()
+	}
+	if cfg.URLhaus {
+		stop := cfg.Timer.start(domain, "urlhaus-check")
+		fmt.Fprintf(status, "\nChecking URLhaus for %s...\n", domain)
+		checkAllURLhaus(hosts, cfg.Output)
+		stop()
+	}
+	if cfg.SecurityHeaders {
+		stop := cfg.Timer.start(domain, "security-headers")
+		fmt.Fprintf(status, "\nChecking security headers for %s...\n", domain)
+		reporters := checkSecurityHeaders(domain, probeHosts, cfg.Output)
+		writeOutput(newFindings(reporters, "hpkp"), cfg.Output, seen)
+		hosts = append(hosts, reporters...)
+		probeHosts = append(probeHosts, reporters...)
+		stop()
+	}
+	if cfg.MeasureAmplification {
+		stop := cfg.Timer.start(domain, "measure-amplification")
+		fmt.Fprintf(status, "\nMeasuring DNS amplification for %s...\n", domain)
+		measureAmplification(domain, hosts)
+		stop()
+	}
+	if cfg.FollowRedirects {
+		stop := cfg.Timer.start(domain, "follow-redirects")
+		fmt.Fprintf(status, "\nFollowing redirects for %s...\n", domain)
+		followAllRedirects(domain, probeHosts, cfg.MaxRedirects)
+		stop()
+	}
+	if cfg.WellKnown {
+		stop := cfg.Timer.start(domain, "well-known")
+		fmt.Fprintf(status, "\nProbing well-known documents for %s...\n", domain)
+		referenced := wellKnownEnumerate(domain, probeHosts)
+		writeOutput(newFindings(referenced, "well-known"), cfg.Output, seen)
+		hosts = append(hosts, referenced...)
+		probeHosts = append(probeHosts, referenced...)
+		stop()
+	}
+	if cfg.JSExtract {
+		stop := cfg.Timer.start(domain, "js-extract")
+		fmt.Fprintf(status, "\nExtracting subdomains from JavaScript for %s...\n", domain)
+		scripted := jsEnumerate(domain, probeHosts)
+		writeOutput(newFindings(scripted, "js"), cfg.Output, seen)
+		hosts = append(hosts, scripted...)
+		probeHosts = append(probeHosts, scripted...)
+		stop()
+	}
+	if cfg.H2Push {
+		stop := cfg.Timer.start(domain, "h2-push")
+		fmt.Fprintf(status, "\nCollecting HTTP/2 push promises for %s...\n", domain)
+		pushed := h2PushEnumerate(domain, probeHosts)
+		writeOutput(newFindings(pushed, "h2-push"), cfg.Output, seen)
+		hosts = append(hosts, pushed...)
+		probeHosts = append(probeHosts, pushed...)
+		stop()
+	}
+	if cfg.ReverseDNS {
+		stop := cfg.Timer.start(domain, "reverse-dns")
+		fmt.Fprintf(status, "\nRunning reverse DNS lookups for %s...\n", domain)
+		reversed := reverseDNSEnumerate(domain, hosts, 10)
+		writeOutput(newFindings(reversed, "reverse-dns"), cfg.Output, seen)
+		hosts = append(hosts, reversed...)
+		probeHosts = append(probeHosts, reversed...)
+		stop()
+	}
+	if cfg.ReverseAXFR {
+		stop := cfg.Timer.start(domain, "reverse-axfr")
+		fmt.Fprintf(status, "\nAttempting reverse zone transfers for %s...\n", domain)
+		reversed := reverseZoneEnumerate(domain, hosts)
+		writeOutput(newFindings(reversed, "reverse-axfr"), cfg.Output, seen)
+		hosts = append(hosts, reversed...)
+		probeHosts = append(probeHosts, reversed...)
+		stop()
+	}
+	if cfg.Probe {
+		stop := cfg.Timer.start(domain, "probe")
+		fmt.Fprintf(status, "\nProbing HTTP and HTTPS liveness for %s...\n", domain)
+		live := probeLiveness(probeHosts, max(cfg.Threads, 1), cfg.Output)
+		fmt.Fprintf(status, " - %d of %d hosts answered\n", len(live), len(probeHosts))
+		stop()
+	}
+	if cfg.PathEnum {
+		stop := cfg.Timer.start(domain, "path-enum")
+		fmt.Fprintf(status, "\nEnumerating paths for %s...\n", domain)
+		enumeratePaths(probeHosts, cfg.PathWordlist)
+		stop()
+	}
+	if cfg.ScreenshotDir != "" {
+		stop := cfg.Timer.start(domain, "screenshots")
+		fmt.Fprintf(status, "\nCapturing screenshots for %s...\n", domain)
+		screenshotHosts(domain, probeHosts, cfg.ScreenshotDir)
+		stop()
+	}
+	return hosts, nil
+}
+
+// activeEnumerate runs the core methods that query the domain's own
+// servers and hosts: zone transfers from each nameserver, CNAME chaining
+// and the wordlist brute force of -mode. Transfers, the CNAME chain and
+// DNS brute force share one pipeline of -threads workers, SNI probing
+// follows once it drains. -passive-only skips it.
+func activeEnumerate(ctx context.Context, domain string, nameServers []string, cfg *Config, seen *subdomainSet) []string {
+	opts := wildcardOptions(ctx, domain, cfg)
+	pipeline := newDNSPipeline(cfg.Threads, cfg, opts)
+	// The first successful transfer on any port and nameserver ends the rest
+	axfrCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	begin := time.Now()
+	go func() {
+		defer close(pipeline.in)
+		for _, port := range cfg.AXFRPorts {
+			for _, ns := range nameServers {
+				addr := net.JoinHostPort(ns, port)
+				if _, _, err := net.SplitHostPort(ns); err == nil {
+					// -ns-file entries that name a port are only tried on it
+					if port != cfg.AXFRPorts[0] {
+						continue
+					}
+					addr = ns
+				}
+				pipeline.in <- DNSQuery{Kind: queryAXFR, Domain: domain, Name: addr, ctx: axfrCtx}
+			}
+		}
+		pipeline.in <- DNSQuery{Kind: queryCNAME, Domain: domain, ctx: ctx}
+		if cfg.Mode == subsniax.ModeSNI {
+			return
+		}
+		queued := make(map[string]bool, len(cfg.Wordlist))
+		for _, label := range cfg.Wordlist {
+			if queued[label] || ctx.Err() != nil {
+				continue
+			}
+			queued[label] = true
+			pipeline.in <- DNSQuery{Kind: queryBrute, Domain: domain, Name: label + "." + domain, ctx: ctx}
+		}
+	}()
+
+	stages := "AXFR and CNAME chaining"
+	if cfg.Mode != subsniax.ModeSNI {
+		stages = "AXFR, CNAME chaining and DNS brute force"
+	}
+	fmt.Fprintf(status, "Attempting %s for %s...\n", stages, domain)
+	var found []string
+	var zone []DiscoveryRecord
+	finished := make(map[string]time.Time)
+	for result := range pipeline.out {
+		q := result.Query
+		finished[q.Kind] = time.Now()
+		switch q.Kind {
+		case queryAXFR:
+			if axfrCtx.Err() != nil && result.Err != nil {
+				continue
 			}
+			cfg.Stats.axfr(domain, result.Err == nil)
+			label := domain + " via " + strings.TrimSuffix(q.Name, ":53")
+			if result.Err != nil {
+				fmt.Fprintf(status, "Attempting AXFR on %-35s AXFR failed or timed out.\n", label)
+			} else {
+				cancel()
+				fmt.Fprintf(status, "Attempting AXFR on %-35s [%s] succeeded\n", label, result.Method)
+			}
+			zone = append(zone, result.Records...)
+		case queryCNAME:
+			if result.Err != nil && ctx.Err() == nil {
+				log.Printf("Failed to lookup CNAME for %s: %v\n", domain, result.Err)
+				cfg.Stats.failed(domain)
+			}
+		}
+		writeOutput(result.Findings, cfg.Output, seen)
+		found = append(found, findingNames(result.Findings)...)
+	}
+	for kind, end := range finished {
+		cfg.Timer.record(domain, kind, begin, end)
+	}
+	writeZoneOutput(zone, domain, cfg.ZoneOut)
+
+	if cfg.Mode != subsniax.ModeDNS && ctx.Err() == nil {
+		fmt.Fprintf(status, "\nAttempting SNI enumeration for %s...\n", domain)
+		stop := cfg.Timer.start(domain, subsniax.ModeSNI)
+		found = append(found, sniEnumerate(ctx, domain, domain, found, opts, cfg, seen)...)
+		stop()
+	}
+	return found
+}
+
+// unique returns names with duplicates removed, keeping the first occurrence.
+func unique(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	var result []string
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			result = append(result, name)
 		}
 	}
 	return result
 }
 
-func cnameChain(domain string) []string {
+// bruteModes describes each -mode for the progress header.
+var bruteModes = map[string]string{
+	subsniax.ModeSNI:  "SNI enumeration",
+	subsniax.ModeDNS:  "DNS brute force",
+	subsniax.ModeBoth: "DNS brute force and SNI enumeration",
+}
+
+// bruteEnumerate tests the wordlist under parent, a name at or below
+// domain, as -mode says: by A/AAAA lookup, by TLS handshake, or both. It
+// writes the names that resolve, the endpoints that answer and the names
+// under domain on their certificates that are not in known. Candidates
+// answered only by a wildcard record of parent are skipped. It returns
+// the names.
+func bruteEnumerate(ctx context.Context, parent, domain string, known []string, cfg *Config, seen *subdomainSet) []string {
+	opts := wildcardOptions(ctx, parent, cfg)
+	var names []string
+	if cfg.Mode != subsniax.ModeSNI {
+		resolved := subsniax.DNSEnumerate(ctx, parent, opts)
+		writeOutput(resolved, cfg.Output, seen)
+		names = findingNames(resolved)
+	}
+	if cfg.Mode == subsniax.ModeDNS {
+		return names
+	}
+	return append(names, sniEnumerate(ctx, parent, domain, slices.Concat(known, names), opts, cfg, seen)...)
+}
+
+// wildcardOptions returns the core options for enumerating below parent
+// with the addresses of its wildcard record, unless -no-wildcard-filter
+// is given.
+func wildcardOptions(ctx context.Context, parent string, cfg *Config) subsniax.Options {
+	opts := coreOptions(cfg)
+	if !cfg.NoWildcardFilter {
+		if opts.Wildcard = subsniax.DetectWildcard(ctx, parent, opts); opts.Wildcard != nil {
+			fmt.Fprintf(status, " - [WILDCARD] *.%s resolves to %s, skipping names that only resolve there\n", parent, strings.Join(opts.Wildcard, ", "))
+		}
+	}
+	return opts
+}
+
+// sniEnumerate probes the wordlist below parent over SNI and adds the
+// names on the certificates it sees that are new subdomains of domain.
+func sniEnumerate(ctx context.Context, parent, domain string, known []string, opts subsniax.Options, cfg *Config, seen *subdomainSet) []string {
+	endpoints, certNames := subsniax.SNIEnumerate(ctx, parent, opts)
+	writeOutput(endpoints, cfg.Output, seen)
+	names := findingNames(endpoints)
+	if certNames = subsniax.CertNames(certNames, domain, slices.Concat(known, names)); len(certNames) > 0 {
+		fmt.Fprintf(status, "\nNames from SNI certificates for %s:\n", parent)
+		writeOutput(newFindings(certNames, "sni-cert"), cfg.Output, seen)
+		names = append(names, certNames...)
+	}
+	return names
+}
+
+// enumerateNested treats every subdomain found so far as a parent for
+// another round of AXFR and SNI enumeration, and the names that round
+// finds as parents for the next, until -depth levels below domain are
+// covered. Each parent is only enumerated once.
+func enumerateNested(ctx context.Context, domain string, found, nameServers []string, cfg *Config, seen *subdomainSet) []string {
+	visited := map[string]bool{domain: true}
 	var result []string
-	cnames := make(map[string]bool) // Caching to avoid redundant lookups
-	cname, err := net.LookupCNAME(domain)
-	if err != nil {
-		log.Printf("Failed to lookup CNAME for %s: %v\n", domain, err)
-		return result
+	level := found
+	for depth := 2; depth <= cfg.Depth && ctx.Err() == nil; depth++ {
+		var next []string
+		for _, parent := range unique(level) {
+			parent = strings.ToLower(parent)
+			// Service labels and wildcards never have hosts below them
+			if visited[parent] || !strings.HasSuffix(parent, "."+domain) || strings.HasPrefix(parent, "_") || strings.HasPrefix(parent, "*") {
+				continue
+			}
+			visited[parent] = true
+			if ctx.Err() != nil {
+				break
+			}
+			if len(nameServers) > 0 {
+				addr := nameServers[0]
+				if _, _, err := net.SplitHostPort(addr); err != nil {
+					addr = net.JoinHostPort(addr, cfg.AXFRPorts[0])
+				}
+				records := attemptTransfer(ctx, parent, addr, dnsmessage.TypeAXFR, cfg.Delay)
+				if len(records) > 0 {
+					fmt.Fprintf(status, "Attempting AXFR on %-35s [AXFR] succeeded\n", parent)
+					subdomains := recordFindings(records, "axfr")
+					writeOutput(subdomains, cfg.Output, seen)
+					next = append(next, findingNames(subdomains)...)
+				}
+			}
+			next = append(next, bruteEnumerate(ctx, parent, domain, slices.Concat(found, result), cfg, seen)...)
+		}
+		result = append(result, next...)
+		level = next
+	}
+	return result
+}
+
+// coreOptions configures the subsniax enumeration from cfg and the global
+// resolver, printing SNI endpoints as they answer.
+func coreOptions(cfg *Config) subsniax.Options {
+	return subsniax.Options{
+		Delay:      time.Duration(cfg.Delay) * time.Millisecond,
+		Threads:    cfg.Threads,
+		Resolver:   unpacedResolver(),
+		Limiter:    limiter,
+		Wordlist:   cfg.Wordlist,
+		Mode:       cfg.Mode,
+		Ports:      cfg.SNIPorts,
+		TLSTimeout: tlsTimeout,
+		Progress: func(f Finding) {
+			if f.Source == "sni" {
+				fmt.Fprintln(status, " - SNI detected:", f.Name)
+			}
+		},
 	}
-	for cname != domain {
-		if _, seen := cnames[cname]; seen {
-			break
+}
+
+func transferDelegatedZones(ctx context.Context, domain string, found []string, cfg *Config, seen *subdomainSet) {
+	checked := make(map[string]bool)
+	for _, subdomain := range found {
+		if ctx.Err() != nil {
+			return
+		}
+		if subdomain == domain || checked[subdomain] || !strings.HasSuffix(subdomain, "."+domain) {
+			continue
+		}
+		checked[subdomain] = true
+
+		cut, ns, err := detectZoneCut(subdomain)
+		if err != nil || !cut {
+			continue
+		}
+		fmt.Fprintf(status, "\nZone cut detected at %s, attempting AXFR via %s\n", subdomain, ns)
+		var records []DiscoveryRecord
+		var method string
+		for _, port := range cfg.AXFRPorts {
+			records, method, err = tryAllTransferTypes(ctx, subdomain, net.JoinHostPort(ns, port), cfg.Delay)
+			if err == nil {
+				break
+			}
 		}
-		cnames[cname] = true
-		result = append(result, cname)
-		cname, err = net.LookupCNAME(cname)
+		cfg.Stats.axfr(domain, err == nil)
 		if err != nil {
-			break
+			fmt.Fprintln(status, "AXFR failed or timed out.")
+			continue
 		}
+		fmt.Fprintf(status, "[%s] succeeded\n", method)
+		writeOutput(recordFindings(records, "axfr"), cfg.Output, seen)
+		writeZoneOutput(records, subdomain, cfg.ZoneOut)
 	}
-	return result
 }
 
-func sniEnumerate(domain string, delay int) []string {
-	commonSubdomains := []string{
-		"www", "mail", "ftp", "webmail", "smtp", "portal", "vpn", "api", "dev", "test",
-		"staging", "beta", "alpha", "dev-api", "sandbox", "preprod", "prod", "uat", "qa", "demo",
-		"auth", "login", "register", "signup", "accounts", "user", "profile", "admin", "adminpanel",
-		"help", "support", "docs", "documentation", "contact", "knowledgebase", "kb", "faq",
-		"blog", "news", "media", "static", "images", "img", "cdn", "video", "assets", "resources",
-		"shop", "store", "cart", "checkout", "order", "payments", "billing", "invoice", "pay",
-		"analytics", "track", "tracking", "stats", "metrics", "data", "insights", "reports",
-		"status", "monitor", "dashboard", "gateway", "node", "cdn", "proxy", "edge", "backup",
-		"community", "forum", "discuss", "discussion", "social", "events", "meetup", "groups",
-		"internal", "devtools", "tools", "config", "settings", "configurations",
-		"developers", "developer", "api-docs", "api-portal", "graphql", "rest",
-		"marketing", "promo", "offers", "campaign", "landing", "sales",
-		"client", "userportal", "account", "my", "myaccount", "customer", "members", "portal",
-		"app", "test1", "test2", "api-staging", "dashboard", "console", "manage", "sso", "single-sign-on",
-		"backup", "service", "sync",
+// attemptTransfer requests domain from the nameserver at addr (host:port)
+// with a zone transfer style query of type qtype (AXFR, IXFR or ANY) over
+// the source port set by -src-port. It gives up early once ctx is done.
+// delay is the idle timeout per message in milliseconds, dnsQueryTimeout
+// is used when it is zero.
+func attemptTransfer(ctx context.Context, domain, addr string, qtype dnsmessage.Type, delay int) []DiscoveryRecord {
+	var result []DiscoveryRecord
+	if srcPort != 0 {
+		srcPortMu.Lock()
+		defer srcPortMu.Unlock()
 	}
-	var result []string
-	for _, subdomain := range commonSubdomains {
-		addr := fmt.Sprintf("%s.%s", subdomain, domain)
-		_, err := tls.Dial("tcp", addr+":443", &tls.Config{
-			InsecureSkipVerify: true,
-		})
-		if err == nil {
-			result = append(result, addr)
-			fmt.Println(" - SNI detected:", addr)
+	conn, err := transferDialer().DialContext(ctx, "tcp", addr)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Failed to connect to %s for %s: %v\n", addr, typeName(qtype), srcPortError(err))
 		}
+		return result
+	}
+	defer conn.Close()
+
+	timeout := time.Duration(delay) * time.Millisecond
+	if timeout <= 0 {
+		timeout = dnsQueryTimeout
+	}
+	records, err := subsniax.Transfer(ctx, conn, domain, qtype, dnsClass, timeout)
+	if err != nil && ctx.Err() == nil {
+		log.Printf("%s of %s from %s: %v\n", typeName(qtype), domain, addr, err)
+	}
+	for _, rr := range records {
+		result = append(result, newDiscoveryRecord(rr))
 	}
 	return result
 }
 
-func writeOutput(subdomains []string, output *os.File) {
-	if len(subdomains) > 0 {
-		for _, subdomain := range subdomains {
+// tryAllTransferTypes runs AXFR, IXFR and ANY queries against ns in
+// parallel and returns the records of the first zone transfer to succeed
+// together with the query type that worked, cancelling the others. Nearly
+// every server answers ANY, so its records are only used when neither
+// transfer type produced anything.
+func tryAllTransferTypes(ctx context.Context, domain, addr string, delay int) ([]DiscoveryRecord, string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type outcome struct {
+		qtype   dnsmessage.Type
+		records []DiscoveryRecord
+	}
+	qtypes := []dnsmessage.Type{dnsmessage.TypeAXFR, typeIXFR, dnsmessage.TypeALL}
+	if srcPort != 0 {
+		// Parallel connections to one server from one port would collide
+		qtypes = qtypes[:1]
+	}
+	outcomes := make(chan outcome, len(qtypes))
+	for _, qtype := range qtypes {
+		go func(qtype dnsmessage.Type) {
+			outcomes <- outcome{qtype, attemptTransfer(ctx, domain, addr, qtype, delay)}
+		}(qtype)
+	}
+
+	var fallback []DiscoveryRecord
+	for range qtypes {
+		o := <-outcomes
+		if len(recordNames(o.records)) == 0 {
+			continue
+		}
+		if o.qtype == dnsmessage.TypeALL {
+			fallback = o.records
+			continue
+		}
+		return o.records, typeName(o.qtype), nil
+	}
+	if fallback != nil {
+		return fallback, typeName(dnsmessage.TypeALL), nil
+	}
+	return nil, "", fmt.Errorf("no transfer type succeeded against %s", addr)
+}
+
+// sniProbe reports whether addr completes a TLS handshake on port 443.
+func sniProbe(addr string) bool {
+	_, ok := subsniax.ProbeSNI(context.Background(), addr, "443", subsniax.Options{Resolver: unpacedResolver(), Limiter: limiter, TLSTimeout: tlsTimeout})
+	return ok
+}
+
+// tlsTimeout bounds the connect and handshake of every SNI probe.
+var tlsTimeout = 5 * time.Second
+
+// status receives progress and analysis messages. In -sublist3r mode it is
+// stderr, so stdout carries nothing but the bare subdomains.
+var status io.Writer = os.Stdout
+
+// verbose enables extra detail such as the remediation advice after an
+// amplification measurement.
+var verbose bool
+
+// bareOutput prints subdomains without the " - " prefix.
+var bareOutput bool
+
+// jsonOutput prints and writes every subdomain as a JSON Result line.
+var jsonOutput bool
+
+// resolveOutput adds the A and AAAA addresses of every subdomain to its
+// output line, resolveWorkers looks them up at once.
+var (
+	resolveOutput  bool
+	resolveWorkers = subsniax.DefaultThreads
+)
+
+// cloudOutput and cdnOutput fill the cloud_provider and cdn_provider
+// fields of every -json result, they are set by -cloud and -cdn.
+var cloudOutput, cdnOutput bool
+
+// Result is one subdomain as written in -json mode.
+type Result struct {
+	Domain        string    `json:"domain,omitempty"`
+	Subdomain     string    `json:"subdomain"`
+	Source        string    `json:"source"`
+	RecordType    string    `json:"record_type,omitempty"`
+	Timestamp     time.Time `json:"timestamp"`
+	IPs           []string  `json:"ips,omitempty"`
+	TLD           string    `json:"tld,omitempty"`
+	CloudProvider string    `json:"cloud_provider,omitempty"`
+	CDNProvider   string    `json:"cdn_provider,omitempty"`
+}
+
+// Finding is a subdomain together with the method that found it.
+type Finding = subsniax.Finding
+
+// newFindings wraps names found by source.
+func newFindings(names []string, source string) []Finding {
+	findings := make([]Finding, len(names))
+	for i, name := range names {
+		findings[i] = Finding{Name: name, Source: source}
+	}
+	return findings
+}
+
+// findingNames returns the host names of findings, without ports.
+func findingNames(findings []Finding) []string {
+	names := make([]string, len(findings))
+	for i, f := range findings {
+		names[i] = f.Name
+		if host, _, err := net.SplitHostPort(f.Name); err == nil {
+			names[i] = host
+		}
+	}
+	return names
+}
+
+// subdomainSet records the names already written for one domain so every
+// enumeration method can report the same name without repeating it.
+type subdomainSet struct {
+	domain string
+	tld    string // set for siblings added by -expand-tld
+	stats  *Stats // nil outside of a scan
+	mu     sync.Mutex
+	names  map[string]struct{}
+}
+
+func newSubdomainSet(domain string) *subdomainSet {
+	return &subdomainSet{domain: domain, names: make(map[string]struct{})}
+}
+
+// add inserts name and reports whether it was new.
+func (s *subdomainSet) add(name string) bool {
+	key := strings.ToLower(strings.TrimSuffix(name, "."))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.names[key]; ok {
+		return false
+	}
+	s.names[key] = struct{}{}
+	return true
+}
+
+// writeOutput prints findings and appends them to output. With a seen
+// set, names it already holds are skipped. With -resolve the addresses of
+// the new names are looked up first and follow each name.
+func writeOutput(findings []Finding, output *os.File, seen *subdomainSet) {
+	var fresh []Finding
+	for _, finding := range findings {
+		if seen != nil && !seen.add(finding.Name) {
+			continue
+		}
+		if seen != nil && seen.stats != nil {
+			seen.stats.found(seen.domain, finding)
+		}
+		fresh = append(fresh, finding)
+	}
+	if resolveOutput {
+		resolveFindings(fresh, resolveWorkers)
+	}
+	if jsonOutput {
+		results := make([]Result, len(fresh))
+		for i, finding := range fresh {
+			results[i] = newResult(finding, seen)
+		}
+		enrichResults(results, resolveWorkers)
+		for _, result := range results {
+			writeJSONLine(result, result.Subdomain, output)
+		}
+		return
+	}
+	for _, finding := range fresh {
+		subdomain := finding.Name
+		if resolveOutput && len(finding.Addrs) > 0 {
+			subdomain += " [" + strings.Join(finding.Addrs, ", ") + "]"
+		}
+		switch {
+		case bareOutput:
+			fmt.Println(subdomain)
+		case seen != nil && seen.tld != "":
+			fmt.Printf(" - %s [TLD .%s]\n", subdomain, seen.tld)
+		default:
 			fmt.Println(" -", subdomain)
-			if output != nil {
-				output.WriteString(subdomain + "\n")
+		}
+		if output != nil {
+			output.WriteString(subdomain + "\n")
+		}
+	}
+}
+
+// newResult is finding as written in -json mode, without the fields that
+// enrichResults looks up.
+func newResult(finding Finding, seen *subdomainSet) Result {
+	result := Result{
+		Subdomain:  finding.Name,
+		Source:     finding.Source,
+		RecordType: finding.RecordType,
+		Timestamp:  time.Now().UTC(),
+		IPs:        finding.Addrs,
+	}
+	if seen != nil {
+		result.Domain = seen.domain
+		result.TLD = seen.tld
+	}
+	return result
+}
+
+// enrichResults fills the fields of results that need a lookup per host,
+// only those their flags ask for, with workers lookups at once.
+func enrichResults(results []Result, workers int) {
+	if !cloudOutput && !cdnOutput {
+		return
+	}
+	jobs := make(chan *Result)
+	var wg sync.WaitGroup
+	for range max(workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for result := range jobs {
+				host := findingNames([]Finding{{Name: result.Subdomain}})[0]
+				if cloudOutput {
+					result.CloudProvider = cloudProviderOf(host)
+				}
+				if cdnOutput {
+					result.CDNProvider = resultCDN(host, result.IPs)
+				}
 			}
+		}()
+	}
+	for i := range results {
+		jobs <- &results[i]
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// writeJSONLine prints v as one line of JSON and appends it to output,
+// logging name if it cannot be encoded. With -validate-output a line that
+// does not match the output schema is logged and left out, and the run
+// ends with ExitConfigError, so tools consuming the output never read a
+// malformed line.
+func writeJSONLine(v any, name string, output *os.File) {
+	line, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("Failed to encode %s: %v\n", name, err)
+		return
+	}
+	if validateOutput {
+		if err := validateJSONOutput(line); err != nil {
+			log.Printf("JSON output for %s does not match the schema: %v\n", name, err)
+			invalidLines.Add(1)
+			return
 		}
 	}
+	fmt.Println(string(line))
+	if output != nil {
+		output.Write(append(line, '\n'))
+	}
 }
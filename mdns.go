@@ -0,0 +1,168 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+	"golang.org/x/net/ipv4"
+)
+
+const (
+	mdnsServiceEnum = "_services._dns-sd._udp.local."
+	mdnsListenTime  = 2 * time.Second
+)
+
+var mdnsGroup = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+// mdnsBrowse tracks what the responders on the link have announced so far.
+type mdnsBrowse struct {
+	services  map[string]bool
+	instances map[string]bool // instance name to whether its SRV record was seen
+	hosts     map[string]bool
+}
+
+// mdnsDiscover browses the local link for DNS-SD services and returns the
+// hostnames of the instances that answer. It first asks for the service
+// types, then for the instances of each type, and collects SRV targets and
+// address record owners from every response seen on the group.
+func mdnsDiscover() ([]string, error) {
+	conn, err := net.ListenMulticastUDP("udp4", nil, mdnsGroup)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", mdnsGroup, err)
+	}
+	defer conn.Close()
+
+	p := ipv4.NewPacketConn(conn)
+	p.SetMulticastTTL(255)
+	p.SetMulticastLoopback(true)
+	ifaces, _ := net.Interfaces()
+	for i := range ifaces {
+		if ifaces[i].Flags&net.FlagUp != 0 && ifaces[i].Flags&net.FlagMulticast != 0 {
+			p.JoinGroup(&ifaces[i], mdnsGroup)
+		}
+	}
+
+	browse := &mdnsBrowse{
+		services:  make(map[string]bool),
+		instances: make(map[string]bool),
+		hosts:     make(map[string]bool),
+	}
+	if err := mdnsQuery(p, mdnsServiceEnum, dnsmessage.TypePTR); err != nil {
+		return nil, err
+	}
+	browse.collect(p, time.Now().Add(mdnsListenTime))
+
+	for service := range browse.services {
+		if err := mdnsQuery(p, service, dnsmessage.TypePTR); err != nil {
+			return nil, err
+		}
+	}
+	browse.collect(p, time.Now().Add(mdnsListenTime))
+
+	// Responders usually attach the SRV records to the PTR answers, ask
+	// explicitly for the instances that came without one.
+	var pending bool
+	for instance, resolved := range browse.instances {
+		if !resolved {
+			pending = true
+			if err := mdnsQuery(p, instance, dnsmessage.TypeSRV); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if pending {
+		browse.collect(p, time.Now().Add(mdnsListenTime))
+	}
+
+	hosts := make([]string, 0, len(browse.hosts))
+	for host := range browse.hosts {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	return hosts, nil
+}
+
+func mdnsQuery(p *ipv4.PacketConn, name string, qtype dnsmessage.Type) error {
+	qname, err := dnsmessage.NewName(name)
+	if err != nil {
+		return err
+	}
+	msg := dnsmessage.Message{
+		Questions: []dnsmessage.Question{
+			{Name: qname, Type: qtype, Class: dnsmessage.ClassINET},
+		},
+	}
+	query, err := msg.Pack()
+	if err != nil {
+		return err
+	}
+	if _, err := p.WriteTo(query, nil, mdnsGroup); err != nil {
+		return fmt.Errorf("failed to send mDNS query for %s: %w", name, err)
+	}
+	return nil
+}
+
+// collect reads responses from the group until deadline.
+func (b *mdnsBrowse) collect(p *ipv4.PacketConn, deadline time.Time) {
+	p.SetReadDeadline(deadline)
+	buf := make([]byte, 9000)
+	for {
+		n, _, _, err := p.ReadFrom(buf)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return
+			}
+			continue
+		}
+		var msg dnsmessage.Message
+		if err := msg.Unpack(buf[:n]); err != nil || !msg.Header.Response {
+			continue
+		}
+		for _, section := range [][]dnsmessage.Resource{msg.Answers, msg.Additionals} {
+			for _, rr := range section {
+				b.add(rr)
+			}
+		}
+	}
+}
+
+func (b *mdnsBrowse) add(rr dnsmessage.Resource) {
+	owner := rr.Header.Name.String()
+	switch body := rr.Body.(type) {
+	case *dnsmessage.PTRResource:
+		if strings.EqualFold(owner, mdnsServiceEnum) {
+			b.services[body.PTR.String()] = true
+		} else if !b.instances[body.PTR.String()] {
+			b.instances[body.PTR.String()] = false
+		}
+	case *dnsmessage.SRVResource:
+		b.instances[owner] = true
+		b.hosts[mdnsHost(body.Target.String())] = true
+	case *dnsmessage.AResource, *dnsmessage.AAAAResource:
+		b.hosts[mdnsHost(owner)] = true
+	}
+}
+
+func mdnsHost(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
+
+// mdnsEnumerate prints the hostnames found by mdnsDiscover.
+func mdnsEnumerate() []string {
+	hosts, err := mdnsDiscover()
+	if err != nil {
+		log.Printf("mDNS discovery failed: %v\n", err)
+		return nil
+	}
+	for _, host := range hosts {
+		fmt.Fprintf(status, " - [MDNS] %s\n", host)
+	}
+	return hosts
+}
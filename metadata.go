@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// metadataParams are query parameters web apps commonly fetch a URL from.
+var metadataParams = []string{"url", "uri", "target", "dest", "redirect", "image", "proxy", "feed"}
+
+// metadataTargets are the cloud metadata endpoints requested through each
+// parameter. GCP and Azure refuse requests without their metadata header,
+// but the refusal itself is distinctive enough to prove the fetch.
+// 169.254.169.254/metadata/v1 is the DigitalOcean layout, Azure lives
+// under /metadata/instance.
+var metadataTargets = []struct {
+	provider string
+	url      string
+	markers  []string
+}{
+	{"AWS", "http://169.254.169.254/latest/meta-data/", []string{"ami-id", "instance-id", "iam/"}},
+	{"GCP", "http://metadata.google.internal/computeMetadata/v1/", []string{"Metadata-Flavor", "instance/", "project/"}},
+	{"Azure", "http://169.254.169.254/metadata/instance?api-version=2021-02-01", []string{"Required metadata header not specified", "\"compute\""}},
+	{"DigitalOcean", "http://169.254.169.254/metadata/v1/", []string{"droplet_id", "interfaces/"}},
+}
+
+// CloudMetadataFinding is a parameter through which host fetched a cloud
+// metadata endpoint. Provider is empty when no parameter did.
+type CloudMetadataFinding struct {
+	Provider  string
+	Parameter string
+	URL       string
+	Evidence  string
+}
+
+// cloudMetadataProbe passes each metadata URL to host through the common
+// URL parameters and looks for metadata content reflected in the response.
+// Markers already present on the unmodified page are ignored.
+func cloudMetadataProbe(host string) (CloudMetadataFinding, error) {
+	baseline, err := metadataFetch("https://" + host + "/")
+	if err != nil {
+		return CloudMetadataFinding{}, err
+	}
+	for _, target := range metadataTargets {
+		for _, param := range metadataParams {
+			probe := "https://" + host + "/?" + param + "=" + url.QueryEscape(target.url)
+			body, err := metadataFetch(probe)
+			if err != nil {
+				continue
+			}
+			for _, marker := range target.markers {
+				if strings.Contains(body, marker) && !strings.Contains(baseline, marker) {
+					return CloudMetadataFinding{
+						Provider:  target.provider,
+						Parameter: param,
+						URL:       probe,
+						Evidence:  marker,
+					}, nil
+				}
+			}
+		}
+	}
+	return CloudMetadataFinding{}, nil
+}
+
+func metadataFetch(rawURL string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := probeClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	return string(body), err
+}
+
+// checkCloudMetadata probes every host and prints the ones that fetched a
+// metadata endpoint on request.
+func checkCloudMetadata(hosts []string) {
+	for _, host := range hosts {
+		finding, err := cloudMetadataProbe(host)
+		if err != nil || finding.Provider == "" {
+			continue
+		}
+		fmt.Fprintf(status, " - [CLOUD-METADATA: %s] %s via ?%s= (matched %q)\n", finding.Provider, host, finding.Parameter, finding.Evidence)
+	}
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	colorReset  = "\033[0m"
+)
+
+// MonitorState is the result set of the last monitoring run, persisted so
+// a restarted monitor keeps diffing against what it saw before.
+type MonitorState struct {
+	Updated    time.Time           `json:"updated"`
+	Subdomains map[string][]string `json:"subdomains"`
+}
+
+// MonitorDiff is what changed for one domain between two runs. It is also
+// the body posted to -alert-webhook.
+type MonitorDiff struct {
+	Domain  string    `json:"domain"`
+	Time    time.Time `json:"time"`
+	Added   []string  `json:"added,omitempty"`
+	Removed []string  `json:"removed,omitempty"`
+}
+
+func loadMonitorState(path string) (*MonitorState, error) {
+	state := &MonitorState{Subdomains: make(map[string][]string)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("corrupt monitor state %s: %w", path, err)
+	}
+	if state.Subdomains == nil {
+		state.Subdomains = make(map[string][]string)
+	}
+	return state, nil
+}
+
+// save writes the state through a temporary file so an interrupted write
+// never leaves a truncated state behind.
+func (s *MonitorState) save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func diffSubdomains(domain string, previous, current []string) MonitorDiff {
+	diff := MonitorDiff{Domain: domain, Time: time.Now().UTC()}
+	for _, name := range current {
+		if !slices.Contains(previous, name) {
+			diff.Added = append(diff.Added, name)
+		}
+	}
+	for _, name := range previous {
+		if !slices.Contains(current, name) {
+			diff.Removed = append(diff.Removed, name)
+		}
+	}
+	return diff
+}
+
+func postWebhook(url string, diff MonitorDiff) error {
+	body, err := json.Marshal(diff)
+	if err != nil {
+		return err
+	}
+	resp, err := apiClient.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// soaSerial asks the nameserver ns directly for the SOA serial of domain.
+func soaSerial(domain, ns string) (uint32, error) {
+	resp, err := rawQuery(domain, dnsmessage.TypeSOA, ns)
+	if err != nil {
+		return 0, err
+	}
+	for _, rr := range resp.Answers {
+		if soa, ok := rr.Body.(*dnsmessage.SOAResource); ok {
+			return soa.Serial, nil
+		}
+	}
+	return 0, fmt.Errorf("%s returned no SOA record for %s", ns, domain)
+}
+
+// serialWatcher polls the SOA serial of domain on ns every interval and
+// sends each new serial once it differs from the last one seen. The
+// channel is closed when ctx is done.
+func serialWatcher(ctx context.Context, domain, ns string, interval time.Duration) <-chan uint32 {
+	changes := make(chan uint32)
+	go func() {
+		defer close(changes)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		last, err := soaSerial(domain, ns)
+		known := err == nil
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			serial, err := soaSerial(domain, ns)
+			if err != nil {
+				continue
+			}
+			if known && serial != last {
+				select {
+				case changes <- serial:
+				case <-ctx.Done():
+					return
+				}
+			}
+			last, known = serial, true
+		}
+	}()
+	return changes
+}
+
+// serialChange is a new SOA serial seen for domain on the nameserver ns.
+type serialChange struct {
+	domain string
+	ns     string
+	serial uint32
+}
+
+// watchSerials runs a serialWatcher on every nameserver of each domain, or
+// on the -ns-file servers, and merges what they send. It returns nil when
+// interval is not positive, which never delivers anything.
+func watchSerials(ctx context.Context, domains []string, nameServers []string, interval time.Duration) <-chan serialChange {
+	if interval <= 0 {
+		return nil
+	}
+	changes := make(chan serialChange)
+	var wg sync.WaitGroup
+	for _, domain := range domains {
+		domain = normalizeDomain(domain)
+		servers := nameServers
+		if servers == nil {
+			records, err := resolver.LookupNS(ctx, domain)
+			if err != nil {
+				log.Printf("Not watching the SOA serial of %s: %v\n", domain, err)
+				continue
+			}
+			for _, ns := range records {
+				servers = append(servers, ns.Host)
+			}
+		}
+		for _, ns := range servers {
+			wg.Add(1)
+			go func(ns string) {
+				defer wg.Done()
+				for serial := range serialWatcher(ctx, domain, ns, interval) {
+					select {
+					case changes <- serialChange{domain, ns, serial}:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}(ns)
+		}
+	}
+	go func() {
+		wg.Wait()
+		close(changes)
+	}()
+	return changes
+}
+
+// transferChangedZone re-attempts the zone transfer of a domain whose SOA
+// serial changed, from the nameserver that has the new serial, and reports
+// the names it adds to the monitor state. A transfer only shows what the
+// zone holds, so names missing from it are not reported as removed.
+func transferChangedZone(ctx context.Context, change serialChange, cfg *Config, state *MonitorState, statePath, webhook string) {
+	fmt.Fprintf(status, "\nSOA serial of %s on %s changed to %d, attempting AXFR...\n", change.domain, change.ns, change.serial)
+	addr := change.ns
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, cfg.AXFRPorts[0])
+	}
+	records, method, err := tryAllTransferTypes(ctx, change.domain, addr, cfg.Delay)
+	if err != nil {
+		fmt.Fprintln(status, "AXFR failed or timed out.")
+		return
+	}
+	fmt.Fprintf(status, "[%s] succeeded\n", method)
+
+	previous := state.Subdomains[change.domain]
+	current := slices.Concat(previous, findingNames(recordFindings(records, "axfr")))
+	slices.Sort(current)
+	current = slices.Compact(current)
+	diff := diffSubdomains(change.domain, previous, current)
+	state.Subdomains[change.domain] = current
+	reportDiff(diff, webhook)
+	if len(diff.Added) == 0 {
+		return
+	}
+	state.Updated = time.Now().UTC()
+	if err := state.save(statePath); err != nil {
+		log.Printf("Failed to save monitor state: %v\n", err)
+	}
+}
+
+// monitorDomains runs the full enumeration every interval and reports the
+// subdomains that appeared or disappeared since the previous run. The
+// first run of a domain without saved state only records a baseline.
+// Between runs the SOA serial of every nameserver is polled every
+// serialInterval, and a change triggers a zone transfer right away.
+// Cancelling ctx stops the monitor, a run it interrupts is discarded so
+// its partial results never show up as removed subdomains.
+func monitorDomains(ctx context.Context, domains []string, cfg *Config, interval, serialInterval time.Duration, statePath, webhook string) ExitCode {
+	state, err := loadMonitorState(statePath)
+	if err != nil {
+		log.Println(err)
+		return ExitConfigError
+	}
+	changes := watchSerials(ctx, domains, cfg.NameServers, serialInterval)
+
+	for {
+		found, failed := scanDomains(ctx, domains, cfg)
+		if ctx.Err() != nil {
+			fmt.Fprintln(status, "\nMonitor stopped, the interrupted run was not saved")
+			return ExitSuccess
+		}
+		if len(failed) == len(domains) {
+			log.Println("Every domain failed to enumerate, keeping the previous state")
+		} else {
+			for _, domain := range domains {
+				// A domain that was scanned but found nothing is still diffed,
+				// only the ones that failed keep their previous state
+				domain = normalizeDomain(domain)
+				if failed[domain] {
+					continue
+				}
+				current := found[domain]
+				slices.Sort(current)
+				current = slices.Compact(current)
+				previous, seen := state.Subdomains[domain]
+				state.Subdomains[domain] = current
+				if !seen {
+					fmt.Fprintf(status, "\nBaseline for %s: %d subdomains\n", domain, len(current))
+					continue
+				}
+				reportDiff(diffSubdomains(domain, previous, current), webhook)
+			}
+			state.Updated = time.Now().UTC()
+			if err := state.save(statePath); err != nil {
+				log.Printf("Failed to save monitor state: %v\n", err)
+			}
+		}
+
+		fmt.Fprintf(status, "\nNext run at %s\n", time.Now().Add(interval).Format(time.TimeOnly))
+		next := time.After(interval)
+	wait:
+		for {
+			select {
+			case <-ctx.Done():
+				fmt.Fprintln(status, "\nMonitor stopped")
+				return ExitSuccess
+			case change, ok := <-changes:
+				if !ok {
+					changes = nil
+					continue
+				}
+				transferChangedZone(ctx, change, cfg, state, statePath, webhook)
+			case <-next:
+				break wait
+			}
+		}
+	}
+}
+
+func reportDiff(diff MonitorDiff, webhook string) {
+	for _, name := range diff.Added {
+		fmt.Fprintf(status, "%s[ALERT] new subdomain %s%s\n", colorGreen, name, colorReset)
+	}
+	for _, name := range diff.Removed {
+		fmt.Fprintf(status, "%s[WARNING] subdomain gone %s%s\n", colorYellow, name, colorReset)
+	}
+	if webhook == "" || (len(diff.Added) == 0 && len(diff.Removed) == 0) {
+		return
+	}
+	if err := postWebhook(webhook, diff); err != nil {
+		log.Printf("Failed to post diff for %s to webhook: %v\n", diff.Domain, err)
+	}
+}
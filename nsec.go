@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// maxNSECSteps bounds a zone walk, so a server answering with a chain that
+// never returns to the apex cannot keep it going forever.
+const maxNSECSteps = 10000
+
+// nsecNext asks ns for the NSEC record owned by name and returns the next
+// owner name it points to, lowercase and without the trailing dot.
+func nsecNext(name, ns string) (string, bool, error) {
+	resp, err := rawQuery(name, typeNSEC, ns)
+	if err != nil {
+		return "", false, err
+	}
+	for _, rr := range resp.Answers {
+		body, ok := rr.Body.(*dnsmessage.UnknownResource)
+		if !ok || rr.Header.Type != typeNSEC || !strings.EqualFold(strings.TrimSuffix(rr.Header.Name.String(), "."), name) {
+			continue
+		}
+		text, err := nsecRDATA(&rdataReader{data: body.Data})
+		if err != nil {
+			return "", false, fmt.Errorf("malformed NSEC record for %s: %w", name, err)
+		}
+		next, _, _ := strings.Cut(text, " ")
+		return strings.ToLower(strings.TrimSuffix(next, ".")), true, nil
+	}
+	return "", false, nil
+}
+
+// walkNSEC follows the NSEC chain of domain on the nameserver ns from the
+// apex until it wraps around, returning every owner name on the way. Zones
+// signed with NSEC3, or not signed at all, have no chain to follow.
+func walkNSEC(domain, ns string) ([]string, error) {
+	seen := map[string]bool{domain: true}
+	var names []string
+	current := domain
+	for range maxNSECSteps {
+		next, ok, err := nsecNext(current, ns)
+		if err != nil {
+			return names, err
+		}
+		if !ok {
+			if current == domain {
+				return nil, fmt.Errorf("%s serves no NSEC record for %s, the zone is unsigned or uses NSEC3", ns, domain)
+			}
+			return names, fmt.Errorf("NSEC chain of %s broke off at %s", domain, current)
+		}
+		if seen[next] || !strings.HasSuffix(next, "."+domain) {
+			return names, nil
+		}
+		seen[next] = true
+		if !strings.HasPrefix(next, "*.") {
+			names = append(names, next)
+			fmt.Fprintf(status, " - [NSEC] %s\n", next)
+		}
+		current = next
+	}
+	return names, fmt.Errorf("NSEC walk of %s stopped after %d names", domain, maxNSECSteps)
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// openIntelSource is the OpenIntel measurement data to search: the URL of a
+// daily Parquet dump, or a local .parquet file or directory of them. It is
+// set by -openintel, and openIntelKey by -openintel-key.
+var openIntelSource, openIntelKey string
+
+// openIntelTimeout bounds the download of a remote measurement dump.
+const openIntelTimeout = 10 * time.Minute
+
+// openIntelRow holds the name columns of an OpenIntel forward DNS
+// measurement, the others are not read.
+type openIntelRow struct {
+	QueryName    string `parquet:"query_name,optional"`
+	ResponseName string `parquet:"response_name,optional"`
+	CNAMEName    string `parquet:"cname_name,optional"`
+}
+
+// queryOpenIntel returns the subdomains of domain found in the OpenIntel
+// measurements at openIntelSource. Remote dumps need institutional access
+// and are fetched with openIntelKey, local files are read as they are so
+// saved dumps can be searched offline.
+func queryOpenIntel(domain string) ([]string, error) {
+	var files []string
+	if strings.HasPrefix(openIntelSource, "https://") || strings.HasPrefix(openIntelSource, "http://") {
+		if openIntelKey == "" {
+			return nil, errors.New("remote OpenIntel dumps need an access key, set -openintel-key")
+		}
+		path, err := downloadOpenIntel(openIntelSource, openIntelKey)
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(path)
+		files = []string{path}
+	} else {
+		info, err := os.Stat(openIntelSource)
+		if err != nil {
+			return nil, err
+		}
+		files = []string{openIntelSource}
+		if info.IsDir() {
+			files, err = filepath.Glob(filepath.Join(openIntelSource, "*.parquet"))
+			if err != nil {
+				return nil, err
+			}
+			if len(files) == 0 {
+				return nil, fmt.Errorf("no .parquet files in %s", openIntelSource)
+			}
+		}
+	}
+
+	seen := make(map[string]bool)
+	var result []string
+	for _, file := range files {
+		names, err := readOpenIntel(file, domain)
+		if err != nil {
+			return result, fmt.Errorf("%s: %w", file, err)
+		}
+		for _, name := range names {
+			if !seen[name] {
+				seen[name] = true
+				result = append(result, name)
+			}
+		}
+	}
+	return result, nil
+}
+
+// readOpenIntel returns the names under domain in the query, response and
+// CNAME columns of the Parquet file at path.
+func readOpenIntel(path, domain string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	file, err := parquet.OpenFile(f, info.Size())
+	if err != nil {
+		return nil, err
+	}
+	reader := parquet.NewGenericReader[openIntelRow](file)
+	defer reader.Close()
+
+	suffix := "." + strings.ToLower(domain)
+	var result []string
+	rows := make([]openIntelRow, 1024)
+	for {
+		n, err := reader.Read(rows)
+		for _, row := range rows[:n] {
+			for _, name := range []string{row.QueryName, row.ResponseName, row.CNAMEName} {
+				name = strings.ToLower(strings.TrimSuffix(name, "."))
+				if strings.HasSuffix(name, suffix) {
+					result = append(result, name)
+				}
+			}
+		}
+		if err == io.EOF {
+			return result, nil
+		}
+		if err != nil {
+			return result, err
+		}
+	}
+}
+
+// downloadOpenIntel saves the dump at url to a temporary file, which the
+// caller removes, and returns its path.
+func downloadOpenIntel(url, key string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+key)
+	client := &http.Client{Timeout: openIntelTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("OpenIntel returned %s", resp.Status)
+	}
+
+	f, err := os.CreateTemp("", "sub_sniaX-openintel-*.parquet")
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// measurement is a cut-down OpenIntel forward DNS row, with columns the
+// reader skips.
+type measurement struct {
+	QueryType    string `parquet:"query_type"`
+	QueryName    string `parquet:"query_name"`
+	ResponseType string `parquet:"response_type,optional"`
+	ResponseName string `parquet:"response_name,optional"`
+	CNAMEName    string `parquet:"cname_name,optional"`
+	IP4Address   string `parquet:"ip4_address,optional"`
+}
+
+func writeMeasurements(t *testing.T, path string, rows []measurement) {
+	t.Helper()
+	if err := parquet.WriteFile(path, rows); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQueryOpenIntel(t *testing.T) {
+	dir := t.TempDir()
+	writeMeasurements(t, filepath.Join(dir, "a.parquet"), []measurement{
+		{QueryType: "A", QueryName: "www.example.com.", ResponseType: "A", ResponseName: "www.example.com.", IP4Address: "192.0.2.1"},
+		{QueryType: "A", QueryName: "shop.example.com.", ResponseType: "CNAME", ResponseName: "shop.example.com.", CNAMEName: "edge.cdn.example.com."},
+		{QueryType: "A", QueryName: "www.example.net.", ResponseType: "A", ResponseName: "www.example.net."},
+		{QueryType: "NS", QueryName: "example.com."},
+	})
+	writeMeasurements(t, filepath.Join(dir, "b.parquet"), []measurement{
+		{QueryType: "AAAA", QueryName: "API.Example.com."},
+		{QueryType: "A", QueryName: "www.example.com."},
+	})
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a dump"), 0o644)
+	want := []string{"www.example.com", "shop.example.com", "edge.cdn.example.com", "api.example.com"}
+
+	dump, err := os.ReadFile(filepath.Join(dir, "a.parquet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		w.Write(dump)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name, source, key string
+		want              []string
+		wantErr           bool
+	}{
+		{"directory", dir, "", want, false},
+		{"file", filepath.Join(dir, "b.parquet"), "", []string{"api.example.com", "www.example.com"}, false},
+		{"missing", filepath.Join(dir, "none.parquet"), "", nil, true},
+		{"empty directory", t.TempDir(), "", nil, true},
+		{"not parquet", filepath.Join(dir, "notes.txt"), "", nil, true},
+		{"remote", server.URL + "/dump.parquet", "secret", want[:3], false},
+		{"remote without key", server.URL + "/dump.parquet", "", nil, true},
+		{"remote wrong key", server.URL + "/dump.parquet", "guess", nil, true},
+	}
+
+	for _, tt := range tests {
+		openIntelSource, openIntelKey = tt.source, tt.key
+		got, err := queryOpenIntel("example.com")
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: queryOpenIntel error = %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !slices.Equal(got, tt.want) {
+			t.Errorf("%s: queryOpenIntel = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	openIntelSource, openIntelKey = "", ""
+}
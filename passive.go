@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// errHackerTargetLimit is returned once the free HackerTarget quota is used up.
+var errHackerTargetLimit = errors.New("HackerTarget API count exceeded")
+
+// queryHackerTarget returns the subdomains HackerTarget's host search knows
+// for domain. The API answers with one "subdomain,ip" pair per line.
+func queryHackerTarget(domain string) ([]string, error) {
+	resp, err := apiClient.Get("https://api.hackertarget.com/hostsearch/?q=" + url.QueryEscape(domain))
+	if err != nil {
+		return nil, fmt.Errorf("HackerTarget request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HackerTarget returned %s", resp.Status)
+	}
+
+	var result []string
+	scanner := bufio.NewScanner(io.LimitReader(resp.Body, 16<<20))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "API count exceeded") {
+			return result, errHackerTargetLimit
+		}
+		host, _, ok := strings.Cut(line, ",")
+		if !ok {
+			// Plain-text status such as "No records found" or "error check your search parameter"
+			continue
+		}
+		host = strings.ToLower(strings.TrimSuffix(host, "."))
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			result = append(result, host)
+		}
+	}
+	return result, scanner.Err()
+}
+
+// crtshTimeout bounds a crt.sh query, which for large domains often takes
+// far longer than the other APIs.
+const crtshTimeout = 90 * time.Second
+
+// queryCrtSh returns the names under domain on the certificates crt.sh has
+// indexed from CT logs. Each entry's name_value holds one name per line.
+func queryCrtSh(domain string) ([]string, error) {
+	client := *apiClient
+	client.Timeout = crtshTimeout
+	resp, err := client.Get("https://crt.sh/?output=json&q=" + url.QueryEscape("%."+domain))
+	if err != nil {
+		return nil, fmt.Errorf("crt.sh request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("crt.sh returned %s", resp.Status)
+	}
+
+	var entries []struct {
+		NameValue string `json:"name_value"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("failed to decode crt.sh response: %w", err)
+	}
+	seen := make(map[string]bool)
+	var result []string
+	for _, entry := range entries {
+		for _, name := range strings.Split(entry.NameValue, "\n") {
+			name = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(name)), "*.")
+			if !seen[name] && (name == domain || strings.HasSuffix(name, "."+domain)) {
+				seen[name] = true
+				result = append(result, name)
+			}
+		}
+	}
+	return result, nil
+}
+
+// queryCertSpotter returns the names under domain on the certificates
+// CertSpotter has seen in CT logs. Without an API key the hourly quota is
+// small, hitting it returns what was collected so far together with the
+// error.
+func queryCertSpotter(domain string) ([]string, error) {
+	names, err := certSpotterNames(domain)
+	var result []string
+	for _, name := range names {
+		if name == domain || strings.HasSuffix(name, "."+domain) {
+			result = append(result, name)
+		}
+	}
+	return result, err
+}
+
+// certSpotterNames returns every name on the certificates CertSpotter has
+// logged for domain and its subdomains, including SANs for other domains
+// sharing the certificate. Results come a page at a time, each request
+// continues after the id of the last issuance returned.
+func certSpotterNames(domain string) ([]string, error) {
+	seen := make(map[string]bool)
+	var result []string
+	after := ""
+	for {
+		query := url.Values{
+			"domain":             {domain},
+			"include_subdomains": {"true"},
+			"expand":             {"dns_names"},
+		}
+		if after != "" {
+			query.Set("after", after)
+		}
+		resp, err := apiClient.Get("https://api.certspotter.com/v1/issuances?" + query.Encode())
+		if err != nil {
+			return result, fmt.Errorf("CertSpotter request failed: %w", err)
+		}
+		var issuances []struct {
+			ID       string   `json:"id"`
+			DNSNames []string `json:"dns_names"`
+		}
+		switch resp.StatusCode {
+		case http.StatusOK:
+			err = json.NewDecoder(resp.Body).Decode(&issuances)
+		case http.StatusTooManyRequests:
+			err = errors.New("CertSpotter rate limit reached (429)")
+		default:
+			err = fmt.Errorf("CertSpotter returned %s", resp.Status)
+		}
+		resp.Body.Close()
+		if err != nil {
+			return result, err
+		}
+		if len(issuances) == 0 {
+			return result, nil
+		}
+
+		for _, issuance := range issuances {
+			for _, name := range issuance.DNSNames {
+				name = strings.TrimPrefix(strings.ToLower(strings.TrimSuffix(name, ".")), "*.")
+				if !seen[name] {
+					seen[name] = true
+					result = append(result, name)
+				}
+			}
+		}
+		after = issuances[len(issuances)-1].ID
+	}
+}
+
+// queryUmbrella returns the subdomains Cisco Umbrella Investigate has seen
+// in passive DNS for domain.
+func queryUmbrella(domain, apiKey string) ([]string, error) {
+	req, err := http.NewRequest(http.MethodGet, "https://investigate.api.umbrella.com/subdomains/"+url.PathEscape(domain), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+
+	resp, err := apiClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Umbrella request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized:
+		return nil, errors.New("Umbrella rejected the API key (401), check -umbrella-key")
+	case http.StatusForbidden:
+		return nil, errors.New("Umbrella API key lacks Investigate access (403), the subdomains endpoint needs an Investigate plan")
+	default:
+		return nil, fmt.Errorf("Umbrella returned %s", resp.Status)
+	}
+
+	var entries []struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("failed to decode Umbrella response: %w", err)
+	}
+	var result []string
+	for _, entry := range entries {
+		name := strings.ToLower(strings.TrimSuffix(entry.Name, "."))
+		if name == "" {
+			continue
+		}
+		if name != domain && !strings.HasSuffix(name, "."+domain) {
+			name += "." + domain
+		}
+		result = append(result, name)
+	}
+	return result, nil
+}
+
+const (
+	pastebinScrapeList = "https://scrape.pastebin.com/api_scraping.php?limit=250"
+	pastebinScrapeItem = "https://scrape.pastebin.com/api_scrape_item.php?i="
+	otxPassiveDNS      = "https://otx.alienvault.com/api/v1/indicators/domain/%s/passive_dns"
+)
+
+// queryPasteSites looks for subdomains of domain leaked in the latest
+// Pastebin pastes and in AlienVault OTX. Pastebin only serves its scraping
+// API to PRO accounts from a whitelisted IP, so it needs no key here. OTX
+// works anonymously at a lower rate limit, apiKeys["otx"] lifts it. The
+// names found by either source are returned even if the other one fails.
+func queryPasteSites(domain string, apiKeys map[string]string) ([]string, error) {
+	pastes, pastebinErr := scrapePastebin(domain)
+	otx, otxErr := queryOTX(domain, apiKeys["otx"])
+
+	seen := make(map[string]bool)
+	var result []string
+	for _, name := range append(pastes, otx...) {
+		if !seen[name] {
+			seen[name] = true
+			result = append(result, name)
+		}
+	}
+	return result, errors.Join(pastebinErr, otxErr)
+}
+
+func scrapePastebin(domain string) ([]string, error) {
+	resp, err := apiClient.Get(pastebinScrapeList)
+	if err != nil {
+		return nil, fmt.Errorf("Pastebin request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return nil, fmt.Errorf("Pastebin request failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || strings.Contains(string(body), "DOES NOT HAVE ACCESS") {
+		return nil, errors.New("Pastebin refused the scraping request, it needs a PRO account with this IP whitelisted")
+	}
+
+	var pastes []struct {
+		Key string `json:"key"`
+	}
+	if err := json.Unmarshal(body, &pastes); err != nil {
+		return nil, fmt.Errorf("failed to decode Pastebin response: %w", err)
+	}
+	pattern := subdomainPattern(domain)
+	var result []string
+	for _, paste := range pastes {
+		resp, err := apiClient.Get(pastebinScrapeItem + url.QueryEscape(paste.Key))
+		if err != nil {
+			continue
+		}
+		text, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		resp.Body.Close()
+		result = append(result, matchSubdomains(pattern, string(text), domain)...)
+	}
+	return result, nil
+}
+
+func queryOTX(domain, apiKey string) ([]string, error) {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf(otxPassiveDNS, url.PathEscape(domain)), nil)
+	if err != nil {
+		return nil, err
+	}
+	if apiKey != "" {
+		req.Header.Set("X-OTX-API-KEY", apiKey)
+	}
+	resp, err := apiClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("OTX request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("OTX returned %s", resp.Status)
+	}
+
+	var records struct {
+		PassiveDNS []struct {
+			Hostname string `json:"hostname"`
+		} `json:"passive_dns"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&records); err != nil {
+		return nil, fmt.Errorf("failed to decode OTX response: %w", err)
+	}
+	var result []string
+	for _, record := range records.PassiveDNS {
+		name := strings.ToLower(strings.TrimSuffix(record.Hostname, "."))
+		if strings.HasSuffix(name, "."+domain) {
+			result = append(result, name)
+		}
+	}
+	return result, nil
+}
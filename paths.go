@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// defaultPaths is probed when -path-enum is given without -path-wordlist.
+var defaultPaths = []string{
+	"/admin", "/login", "/dashboard", "/api", "/api/v1", "/api/v2", "/graphql",
+	"/swagger", "/swagger-ui.html", "/openapi.json", "/actuator", "/actuator/health",
+	"/server-status", "/phpinfo.php", "/wp-admin", "/wp-login.php", "/debug",
+	"/console", "/metrics", "/.git/config", "/.env", "/.DS_Store", "/backup",
+	"/robots.txt", "/sitemap.xml", "/crossdomain.xml", "/config.json",
+}
+
+// PathResult is a path that answered with something other than 404.
+type PathResult struct {
+	Path   string `json:"path"`
+	Status int    `json:"status"`
+	Length int64  `json:"length"`
+}
+
+// loadWordlist reads one path per line from file, ignoring blank lines and
+// comments and adding the leading slash where it is missing.
+func loadWordlist(file string) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var paths []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !strings.HasPrefix(line, "/") {
+			line = "/" + line
+		}
+		paths = append(paths, line)
+	}
+	return paths, scanner.Err()
+}
+
+// pathEnumerate requests every path of wordlist on host over HTTPS and
+// returns those that did not answer 404.
+func pathEnumerate(host string, wordlist []string) []PathResult {
+	var result []PathResult
+	for _, path := range wordlist {
+		resp, err := probeClient.Get("https://" + host + path)
+		if err != nil {
+			continue
+		}
+		n, _ := io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound {
+			continue
+		}
+		length := resp.ContentLength
+		if length < 0 {
+			length = n
+		}
+		result = append(result, PathResult{Path: path, Status: resp.StatusCode, Length: length})
+	}
+	return result
+}
+
+// enumeratePaths runs pathEnumerate on every host and prints the hits.
+func enumeratePaths(hosts []string, wordlist []string) {
+	for _, host := range hosts {
+		resp, err := probeClient.Head("https://" + host + "/")
+		if err != nil {
+			log.Printf("Skipping path enumeration on %s: %v\n", host, err)
+			continue
+		}
+		resp.Body.Close()
+		for _, hit := range pathEnumerate(host, wordlist) {
+			fmt.Fprintf(status, " - [PATH] https://%s%s %d (%d bytes)\n", host, hit.Path, hit.Status, hit.Length)
+		}
+	}
+}
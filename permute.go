@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	versionSuffix  = regexp.MustCompile(`^(.*?)[-_]?v(\d+)$`)
+	numberSuffix   = regexp.MustCompile(`^(.*?)[-_]?(\d+)$`)
+	trailingDigits = regexp.MustCompile(`^(.*?[^-_\d])([-_]?)(\d+)$`)
+)
+
+// permuteMax is the highest counter numberPermutations generates.
+var permuteMax = 20
+
+// generatePatternVariants derives numbered and versioned siblings of a
+// discovered name, e.g. dev-api.example.com yields dev-api-1 .. dev-api-9
+// and dev-api-v1 .. dev-api-v5 under the same parent.
+func generatePatternVariants(found string, domain string) []string {
+	rest := strings.TrimSuffix(found, "."+domain)
+	if rest == found || rest == "" {
+		return nil
+	}
+	label, parent, _ := strings.Cut(rest, ".")
+	suffix := "." + domain
+	if parent != "" {
+		suffix = "." + parent + suffix
+	}
+
+	// Strip an existing counter so web-03 and api-v2 expand from their base
+	base := label
+	if m := versionSuffix.FindStringSubmatch(label); m != nil {
+		base = m[1]
+	} else if m := numberSuffix.FindStringSubmatch(label); m != nil {
+		base = m[1]
+	}
+	if base == "" {
+		return nil
+	}
+
+	var variants []string
+	add := func(candidate string) {
+		if candidate != label {
+			variants = append(variants, candidate+suffix)
+		}
+	}
+	for i := 1; i <= 9; i++ {
+		add(fmt.Sprintf("%s-%d", base, i))
+		add(fmt.Sprintf("%s%d", base, i))
+	}
+	for v := 1; v <= 5; v++ {
+		add(fmt.Sprintf("%s-v%d", base, v))
+	}
+	return variants
+}
+
+// numberPermutations expands the trailing counter in the first label of
+// name over 1..permuteMax, both as written and zero-padded, with and
+// without its separator. web-01.example.com yields web-02 .. web-20,
+// web001 .. web020, web1 .. web20 and so on.
+func numberPermutations(name string) []string {
+	label, rest, _ := strings.Cut(name, ".")
+	m := trailingDigits.FindStringSubmatch(label)
+	if m == nil {
+		return nil
+	}
+	base, sep, digits := m[1], m[2], m[3]
+	suffix := ""
+	if rest != "" {
+		suffix = "." + rest
+	}
+
+	seen := map[string]bool{label: true}
+	var result []string
+	for n := 1; n <= permuteMax; n++ {
+		for _, s := range []string{sep, ""} {
+			for _, width := range []int{0, len(digits), 3} {
+				candidate := fmt.Sprintf("%s%s%0*d", base, s, width, n)
+				if !seen[candidate] {
+					seen[candidate] = true
+					result = append(result, candidate+suffix)
+				}
+			}
+		}
+	}
+	return result
+}
+
+// adaptiveEnumerate probes the pattern variants of every name found so far
+// and returns the ones that answer over TLS. Candidates are resolved in
+// bulk first so only names that exist get a TLS handshake.
+func adaptiveEnumerate(domain string, found []string) []string {
+	var candidates []string
+	for _, name := range found {
+		candidates = append(candidates, generatePatternVariants(name, domain)...)
+		candidates = append(candidates, numberPermutations(name)...)
+	}
+	candidates = fuzzyDeduplicate(candidates, found)
+
+	var result []string
+	for _, candidate := range resolvingNames(candidates) {
+		if sniProbe(candidate) {
+			result = append(result, candidate)
+			fmt.Fprintln(status, " - Variant detected:", candidate)
+		}
+	}
+	return result
+}
+
+// fuzzyDeduplicate returns the candidates that do not match an existing
+// name, or an earlier candidate, once both are normalized.
+func fuzzyDeduplicate(candidates []string, existing []string) []string {
+	seen := make(map[string]bool, len(existing))
+	for _, name := range existing {
+		seen[fuzzyKey(name)] = true
+	}
+	var result []string
+	for _, candidate := range candidates {
+		key := fuzzyKey(candidate)
+		if !seen[key] {
+			seen[key] = true
+			result = append(result, candidate)
+		}
+	}
+	return result
+}
+
+// fuzzyKey lowercases name, drops leading and trailing dots and trailing
+// hyphens on every label.
+func fuzzyKey(name string) string {
+	labels := strings.Split(strings.Trim(strings.ToLower(name), "."), ".")
+	for i, label := range labels {
+		labels[i] = strings.TrimRight(label, "-")
+	}
+	return strings.Join(labels, ".")
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestGeneratePatternVariants(t *testing.T) {
+	tests := []struct {
+		found    string
+		count    int
+		has, not []string
+	}{
+		{"dev-api.example.com", 23, []string{"dev-api-1.example.com", "dev-api9.example.com", "dev-api-v5.example.com"}, []string{"dev-api.example.com", "dev-api-v6.example.com"}},
+		{"web-03.example.com", 23, []string{"web-3.example.com", "web1.example.com", "web-v1.example.com"}, []string{"web-03-1.example.com"}},
+		{"api-v2.eu.example.com", 22, []string{"api-1.eu.example.com", "api-v1.eu.example.com"}, []string{"api-v2.eu.example.com", "api-1.example.com"}},
+		{"node7.example.com", 22, []string{"node-7.example.com", "node1.example.com"}, []string{"node7.example.com"}},
+		{"example.com", 0, nil, nil},
+		{"www.example.org", 0, nil, nil},
+		{"123.example.com", 0, nil, nil},
+	}
+	for _, tt := range tests {
+		got := generatePatternVariants(tt.found, "example.com")
+		if len(got) != tt.count {
+			t.Errorf("generatePatternVariants(%q) returned %d variants, want %d: %q", tt.found, len(got), tt.count, got)
+		}
+		for _, name := range tt.has {
+			if !slices.Contains(got, name) {
+				t.Errorf("generatePatternVariants(%q) is missing %q", tt.found, name)
+			}
+		}
+		for _, name := range tt.not {
+			if slices.Contains(got, name) {
+				t.Errorf("generatePatternVariants(%q) contains %q", tt.found, name)
+			}
+		}
+	}
+}
+
+func TestFuzzyKey(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"API.Example.com", "api.example.com"},
+		{".api.example.com.", "api.example.com"},
+		{"api-.example.com", "api.example.com"},
+		{"dev--.api-.example.com", "dev.api.example.com"},
+		{"-api.example.com", "-api.example.com"},
+		{"dev-api.example.com", "dev-api.example.com"},
+	}
+	for _, tt := range tests {
+		if got := fuzzyKey(tt.name); got != tt.want {
+			t.Errorf("fuzzyKey(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFuzzyDeduplicate(t *testing.T) {
+	got := fuzzyDeduplicate([]string{"Dev.example.com", "dev-.example.com", "api.example.com.", "www.example.com"}, []string{"www.example.com"})
+	want := []string{"Dev.example.com", "api.example.com."}
+	if !slices.Equal(got, want) {
+		t.Errorf("fuzzyDeduplicate = %q, want %q", got, want)
+	}
+}
+
+func TestNumberPermutations(t *testing.T) {
+	defer func(max int) { permuteMax = max }(permuteMax)
+	permuteMax = 2
+
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"web-01.example.com", []string{
+			"web-1.example.com", "web-001.example.com", "web1.example.com", "web01.example.com", "web001.example.com",
+			"web-2.example.com", "web-02.example.com", "web-002.example.com", "web2.example.com", "web02.example.com", "web002.example.com",
+		}},
+		{"node7.example.com", []string{
+			"node1.example.com", "node001.example.com",
+			"node2.example.com", "node002.example.com",
+		}},
+		{"db_3", []string{"db_1", "db_001", "db1", "db001", "db_2", "db_002", "db2", "db002"}},
+		{"www.example.com", nil},
+		{"123.example.com", nil},
+		{"api.v2.example.com", nil},
+	}
+	for _, tt := range tests {
+		if got := numberPermutations(tt.name); !slices.Equal(got, tt.want) {
+			t.Errorf("numberPermutations(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"github.com/noob6t5/sub_sniaX/subsniax"
+)
+
+// Kinds of DNSQuery, also the method names their run time is recorded as.
+const (
+	queryAXFR  = "axfr"
+	queryCNAME = "cname"
+	queryBrute = subsniax.ModeDNS
+)
+
+// DNSQuery is one unit of work for the DNS pipeline.
+type DNSQuery struct {
+	Kind   string
+	Domain string
+	// Name is the nameserver host:port of an AXFR and the candidate of a
+	// brute force query, CNAME queries start at Domain.
+	Name string
+	// ctx ends the query, the AXFR queries of a domain share one that the
+	// first successful transfer cancels.
+	ctx context.Context
+}
+
+// DNSResult is the outcome of a DNSQuery. Records and Method are only set
+// for a successful AXFR.
+type DNSResult struct {
+	Query    DNSQuery
+	Findings []Finding
+	Records  []DiscoveryRecord
+	Method   string
+	Err      error
+}
+
+// dnsPipeline runs the DNS queries of several enumeration stages on one
+// pool of workers. Every stage sends its queries to in and reads what they
+// found from out, which is closed once in is closed and drained.
+type dnsPipeline struct {
+	in  chan<- DNSQuery
+	out <-chan DNSResult
+}
+
+// newDNSPipeline starts the scheduler and workers of a pipeline. opts is
+// used for CNAME chains and brute force lookups, including its wildcard.
+func newDNSPipeline(workers int, cfg *Config, opts subsniax.Options) *dnsPipeline {
+	in := make(chan DNSQuery)
+	work := make(chan DNSQuery)
+	out := make(chan DNSResult)
+	go schedule(in, work)
+
+	var wg sync.WaitGroup
+	for range max(workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			queryWorker(work, out, cfg, opts)
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return &dnsPipeline{in: in, out: out}
+}
+
+// schedule queues what arrives on in and hands it to the workers on work,
+// AXFR queries ahead of all others. A transfer returns a whole zone at
+// once, so it should not wait behind thousands of single name lookups.
+func schedule(in <-chan DNSQuery, work chan<- DNSQuery) {
+	defer close(work)
+	var transfers, lookups []DNSQuery
+	for in != nil || len(transfers)+len(lookups) > 0 {
+		var next DNSQuery
+		var send chan<- DNSQuery
+		switch {
+		case len(transfers) > 0:
+			next, send = transfers[0], work
+		case len(lookups) > 0:
+			next, send = lookups[0], work
+		}
+		select {
+		case q, ok := <-in:
+			switch {
+			case !ok:
+				in = nil
+			case q.Kind == queryAXFR:
+				transfers = append(transfers, q)
+			default:
+				lookups = append(lookups, q)
+			}
+		case send <- next:
+			if len(transfers) > 0 {
+				transfers = transfers[1:]
+			} else {
+				lookups = lookups[1:]
+			}
+		}
+	}
+}
+
+// queryWorker runs the queries read from in and sends their results to out.
+func queryWorker(in <-chan DNSQuery, out chan<- DNSResult, cfg *Config, opts subsniax.Options) {
+	for q := range in {
+		result := DNSResult{Query: q}
+		if err := q.ctx.Err(); err != nil {
+			result.Err = err
+			out <- result
+			continue
+		}
+		switch q.Kind {
+		case queryAXFR:
+			result.Records, result.Method, result.Err = tryAllTransferTypes(q.ctx, q.Domain, q.Name, cfg.Delay)
+			result.Findings = recordFindings(result.Records, "axfr")
+		case queryCNAME:
+			result.Findings, result.Err = subsniax.CNAMEChain(q.ctx, q.Domain, opts)
+		case queryBrute:
+			if f, ok := subsniax.ResolveName(q.ctx, q.Name, opts); ok {
+				result.Findings = []Finding{f}
+			}
+		}
+		out <- result
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// ProbeResult is the answer of one host to the liveness probe of a scheme,
+// as written in -json mode.
+type ProbeResult struct {
+	Host       string    `json:"host"`
+	URL        string    `json:"url"`
+	StatusCode int       `json:"status_code"`
+	FinalURL   string    `json:"final_url"`
+	Server     string    `json:"server,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// liveClient follows redirects, unlike probeClient whose transport it
+// shares, so the final URL of a host is known. Its timeout is set by
+// -probe-timeout.
+var liveClient = &http.Client{Transport: probeClient.Transport, Timeout: 10 * time.Second}
+
+// probeURL sends HEAD to u and falls back to GET when the server refuses
+// the method with 405 or 501, as some do. A HEAD that fails is returned as
+// is, retrying a dead host would only double the wait for it.
+func probeURL(u string) (*http.Response, error) {
+	resp, err := liveClient.Head(u)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusMethodNotAllowed && resp.StatusCode != http.StatusNotImplemented {
+		return resp, nil
+	}
+	resp.Body.Close()
+	return liveClient.Get(u)
+}
+
+// probeLiveness requests every host over HTTP and HTTPS with workers at
+// once and prints the hosts that answered with their status, final URL
+// and Server header. In -json mode each answer is also written as a
+// ProbeResult line. It returns the hosts that answered on either scheme.
+func probeLiveness(hosts []string, workers int, output *os.File) []string {
+	jobs := make(chan string)
+	var mu sync.Mutex
+	var live []string
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for host := range jobs {
+				var answered bool
+				for _, scheme := range []string{"https", "http"} {
+					u := scheme + "://" + host + "/"
+					resp, err := probeURL(u)
+					if err != nil {
+						continue
+					}
+					io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+					resp.Body.Close()
+					answered = true
+					result := ProbeResult{
+						Host:       host,
+						URL:        u,
+						StatusCode: resp.StatusCode,
+						FinalURL:   resp.Request.URL.String(),
+						Server:     resp.Header.Get("Server"),
+						Timestamp:  time.Now().UTC(),
+					}
+
+					mu.Lock()
+					line := fmt.Sprintf(" - [LIVE] %s %d", u, result.StatusCode)
+					if result.FinalURL != u {
+						line += " -> " + result.FinalURL
+					}
+					if result.Server != "" {
+						line += " (" + result.Server + ")"
+					}
+					fmt.Fprintln(status, line)
+					if jsonOutput {
+						writeJSONLine(result, host, output)
+					}
+					mu.Unlock()
+				}
+				if answered {
+					mu.Lock()
+					live = append(live, host)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, host := range hosts {
+		jobs <- host
+	}
+	close(jobs)
+	wg.Wait()
+	return live
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/noob6t5/sub_sniaX/subsniax"
+)
+
+// applyProfile sets the flags of the -profile preset on cfg, leaving alone
+// any flag given on the command line:
+//
+//   - fast probes the wordlist over SNI only, 100 at a time, with a short
+//     handshake timeout and no passive sources
+//   - balanced adds the CertSpotter CT logs to SNI and CNAME chaining
+//     with 20 workers
+//   - thorough runs every discovery method, NSEC walking included, and DNS
+//     and SNI brute force two levels deep with 10 workers
+//   - stealth never touches the target, it queries the passive sources
+//     one at a time at half a request per second
+func applyProfile(profile string, cfg *Config) error {
+	explicit := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	switch profile {
+	case "fast":
+		preset(explicit, "mode", &cfg.Mode, subsniax.ModeSNI)
+		preset(explicit, "threads", &cfg.Threads, 100)
+		preset(explicit, "tls-timeout", &tlsTimeout, 2*time.Second)
+		preset(explicit, "depth", &cfg.Depth, 1)
+		for name, source := range passiveSources(cfg) {
+			preset(explicit, name, source, false)
+		}
+	case "balanced":
+		preset(explicit, "mode", &cfg.Mode, subsniax.ModeSNI)
+		preset(explicit, "threads", &cfg.Threads, 20)
+		preset(explicit, "certspotter", &cfg.CertSpotter, true)
+	case "thorough":
+		preset(explicit, "mode", &cfg.Mode, subsniax.ModeBoth)
+		preset(explicit, "threads", &cfg.Threads, 10)
+		preset(explicit, "depth", &cfg.Depth, 2)
+		for name, source := range passiveSources(cfg) {
+			preset(explicit, name, source, true)
+		}
+		for name, method := range map[string]*bool{
+			"dns-sd":       &cfg.DNSSD,
+			"nsec-walk":    &cfg.NSECWalk,
+			"adaptive":     &cfg.Adaptive,
+			"smtp-enum":    &cfg.SMTPEnum,
+			"well-known":   &cfg.WellKnown,
+			"js-extract":   &cfg.JSExtract,
+			"h2-push":      &cfg.H2Push,
+			"reverse-dns":  &cfg.ReverseDNS,
+			"reverse-axfr": &cfg.ReverseAXFR,
+		} {
+			preset(explicit, name, method, true)
+		}
+	case "stealth":
+		preset(explicit, "passive-only", &cfg.PassiveOnly, true)
+		preset(explicit, "threads", &cfg.Threads, 1)
+		preset(explicit, "domain-concurrency", &cfg.DomainConcurrency, 1)
+		preset(explicit, "delay", &cfg.Delay, 5000)
+		preset(explicit, "rps", &requestRate, 0.5)
+		for name, source := range passiveSources(cfg) {
+			preset(explicit, name, source, true)
+		}
+	default:
+		return fmt.Errorf("unknown -profile %q, use fast, balanced, thorough or stealth", profile)
+	}
+	return nil
+}
+
+// passiveSources returns the flags of the passive sources that need no API
+// key, keyed by flag name.
+func passiveSources(cfg *Config) map[string]*bool {
+	return map[string]*bool{
+		"passive":      &cfg.CrtSh,
+		"certspotter":  &cfg.CertSpotter,
+		"hackertarget": &cfg.HackerTarget,
+		"paste-search": &cfg.PasteSearch,
+	}
+}
+
+// preset sets *field to value unless the flag name was given explicitly.
+func preset[T any](explicit map[string]bool, name string, field *T, value T) {
+	if !explicit[name] {
+		*field = value
+	}
+}
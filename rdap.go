@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// rdapBootstrap redirects IP queries to the RDAP server of the responsible
+// RIR, so a single URL works for ARIN, RIPE, APNIC, LACNIC and AFRINIC space.
+const rdapBootstrap = "https://rdap.arin.net/bootstrap/ip/"
+
+// RDAPResult holds the registration of the network an address belongs to.
+type RDAPResult struct {
+	Name   string   `json:"name"`
+	Handle string   `json:"handle"`
+	CIDRs  []string `json:"cidrs"`
+}
+
+// rdapRecord is one address of a host with its registration, as written in
+// -json mode.
+type rdapRecord struct {
+	Host string `json:"host"`
+	IP   string `json:"ip"`
+	RDAPResult
+}
+
+type rdapNetwork struct {
+	Name   string `json:"name"`
+	Handle string `json:"handle"`
+	CIDRs  []struct {
+		V4Prefix string `json:"v4prefix"`
+		V6Prefix string `json:"v6prefix"`
+		Length   int    `json:"length"`
+	} `json:"cidr0_cidrs"`
+}
+
+// rdapLookup returns the registered network covering ip.
+func rdapLookup(ip net.IP) (RDAPResult, error) {
+	req, err := http.NewRequest(http.MethodGet, rdapBootstrap+ip.String(), nil)
+	if err != nil {
+		return RDAPResult{}, err
+	}
+	req.Header.Set("Accept", "application/rdap+json")
+	resp, err := apiClient.Do(req)
+	if err != nil {
+		return RDAPResult{}, fmt.Errorf("RDAP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return RDAPResult{}, fmt.Errorf("RDAP server returned %s", resp.Status)
+	}
+
+	var network rdapNetwork
+	if err := json.NewDecoder(resp.Body).Decode(&network); err != nil {
+		return RDAPResult{}, fmt.Errorf("failed to decode RDAP response: %w", err)
+	}
+	result := RDAPResult{Name: network.Name, Handle: network.Handle}
+	for _, cidr := range network.CIDRs {
+		prefix := cidr.V4Prefix
+		if prefix == "" {
+			prefix = cidr.V6Prefix
+		}
+		if prefix != "" {
+			result.CIDRs = append(result.CIDRs, prefix+"/"+strconv.Itoa(cidr.Length))
+		}
+	}
+	return result, nil
+}
+
+// checkRDAP prints the network registration of every address the hosts
+// resolve to, also as a JSON line per address in -json mode, and returns
+// the results keyed by address.
+func checkRDAP(hosts []string, output *os.File) map[string]RDAPResult {
+	results := make(map[string]RDAPResult)
+	failed := make(map[string]bool)
+	for _, host := range hosts {
+		for _, ip := range lookupIPs(host) {
+			result, ok := results[ip.String()]
+			if !ok {
+				if failed[ip.String()] {
+					continue
+				}
+				var err error
+				result, err = rdapLookup(ip)
+				if err != nil {
+					failed[ip.String()] = true
+					log.Printf("RDAP lookup for %s failed: %v\n", ip, err)
+					continue
+				}
+				results[ip.String()] = result
+			}
+			fmt.Fprintf(status, " - [RDAP] %s (%s) %s %s %s\n", host, ip, result.Name, result.Handle, strings.Join(result.CIDRs, ","))
+			if jsonOutput {
+				writeJSONLine(rdapRecord{Host: host, IP: ip.String(), RDAPResult: result}, host, output)
+			}
+		}
+	}
+	return results
+}
@@ -0,0 +1,939 @@
+package main
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+var errShortRDATA = errors.New("short RDATA")
+
+// parseRawRDATA renders the wire-format RDATA of a record type dnsmessage
+// has no parser for in its standard presentation format. Types that are
+// not understood, or RDATA that fails to parse, fall back to the RFC 3597
+// generic "\# <len> <hex>" form so nothing is silently dropped.
+func parseRawRDATA(rrtype dnsmessage.Type, rdata []byte) string {
+	parse, ok := rdataParsers[uint16(rrtype)]
+	if !ok {
+		return genericRDATA(rdata)
+	}
+	r := &rdataReader{data: rdata}
+	text, err := parse(r)
+	if err != nil || r.off != len(r.data) {
+		return genericRDATA(rdata)
+	}
+	return text
+}
+
+// rdataParsers maps IANA record type numbers to their RDATA decoders.
+var rdataParsers = map[uint16]func(*rdataReader) (string, error){
+	3:     nameRDATA,     // MD
+	4:     nameRDATA,     // MF
+	7:     nameRDATA,     // MB
+	8:     nameRDATA,     // MG
+	9:     nameRDATA,     // MR
+	11:    wksRDATA,      // WKS
+	13:    hinfoRDATA,    // HINFO
+	14:    namesRDATA(2), // MINFO
+	16:    txtRDATA,      // TXT
+	17:    namesRDATA(2), // RP
+	18:    prefNameRDATA, // AFSDB
+	19:    stringsRDATA,  // X25
+	20:    stringsRDATA,  // ISDN
+	21:    prefNameRDATA, // RT
+	22:    nsapRDATA,     // NSAP
+	23:    nameRDATA,     // NSAP-PTR
+	24:    rrsigRDATA,    // SIG
+	25:    dnskeyRDATA,   // KEY
+	26:    pxRDATA,
+	27:    stringsRDATA, // GPOS
+	29:    locRDATA,
+	30:    nxtRDATA,
+	33:    srvRDATA,
+	35:    naptrRDATA,
+	36:    prefNameRDATA, // KX
+	37:    certRDATA,
+	39:    nameRDATA, // DNAME
+	42:    aplRDATA,
+	43:    dsRDATA,
+	44:    sshfpRDATA,
+	45:    ipseckeyRDATA,
+	46:    rrsigRDATA,
+	47:    nsecRDATA,
+	48:    dnskeyRDATA,
+	49:    base64RDATA, // DHCID
+	50:    nsec3RDATA,
+	51:    nsec3paramRDATA,
+	52:    tlsaRDATA,
+	53:    tlsaRDATA, // SMIMEA
+	55:    hipRDATA,
+	59:    dsRDATA,     // CDS
+	60:    dnskeyRDATA, // CDNSKEY
+	61:    base64RDATA, // OPENPGPKEY
+	62:    csyncRDATA,
+	63:    zonemdRDATA,
+	64:    svcbRDATA,
+	65:    svcbRDATA, // HTTPS
+	99:    txtRDATA,  // SPF
+	104:   nidRDATA,
+	105:   l32RDATA,
+	106:   nidRDATA, // L64
+	107:   prefNameRDATA,
+	108:   euiRDATA(6),
+	109:   euiRDATA(8),
+	256:   uriRDATA,
+	257:   caaRDATA,
+	32769: dsRDATA, // DLV
+}
+
+// rdataReader walks the RDATA of a single record.
+type rdataReader struct {
+	data []byte
+	off  int
+}
+
+// remaining reports how many RDATA bytes are left unread.
+func (r *rdataReader) remaining() int { return len(r.data) - r.off }
+
+// bytes consumes the next n bytes, failing with errShortRDATA if fewer remain.
+func (r *rdataReader) bytes(n int) ([]byte, error) {
+	if n < 0 || r.remaining() < n {
+		return nil, errShortRDATA
+	}
+	b := r.data[r.off : r.off+n]
+	r.off += n
+	return b, nil
+}
+
+// rest consumes and returns everything left in the RDATA.
+func (r *rdataReader) rest() []byte {
+	b := r.data[r.off:]
+	r.off = len(r.data)
+	return b
+}
+
+// u8 reads one octet.
+func (r *rdataReader) u8() (uint8, error) {
+	b, err := r.bytes(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+// u16 reads a big-endian 16-bit integer.
+func (r *rdataReader) u16() (uint16, error) {
+	b, err := r.bytes(2)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint16(b), nil
+}
+
+// u32 reads a big-endian 32-bit integer.
+func (r *rdataReader) u32() (uint32, error) {
+	b, err := r.bytes(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(b), nil
+}
+
+// name reads an uncompressed domain name. Compression pointers cannot be
+// followed without the enclosing message, so they are treated as errors.
+func (r *rdataReader) name() (string, error) {
+	var labels []string
+	for {
+		length, err := r.u8()
+		if err != nil {
+			return "", err
+		}
+		if length == 0 {
+			break
+		}
+		if length&0xC0 != 0 {
+			return "", errors.New("compressed name in RDATA")
+		}
+		label, err := r.bytes(int(length))
+		if err != nil {
+			return "", err
+		}
+		labels = append(labels, escapeLabel(label))
+	}
+	return strings.Join(labels, ".") + ".", nil
+}
+
+// charString reads a length-prefixed <character-string>.
+func (r *rdataReader) charString() (string, error) {
+	length, err := r.u8()
+	if err != nil {
+		return "", err
+	}
+	b, err := r.bytes(int(length))
+	if err != nil {
+		return "", err
+	}
+	return quoteTXT(string(b)), nil
+}
+
+// escapeLabel renders a raw label with master-file escaping.
+func escapeLabel(label []byte) string {
+	var b strings.Builder
+	for _, c := range label {
+		switch {
+		case c == '.' || c == '\\' || c == '"' || c == '(' || c == ')' || c == ';' || c == '@' || c == '$':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c <= ' ' || c > '~':
+			fmt.Fprintf(&b, "\\%03d", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// nameRDATA decodes RDATA that is a single domain name.
+func nameRDATA(r *rdataReader) (string, error) {
+	return r.name()
+}
+
+// namesRDATA returns a decoder for RDATA made of n domain names.
+func namesRDATA(n int) func(*rdataReader) (string, error) {
+	return func(r *rdataReader) (string, error) {
+		names := make([]string, n)
+		for i := range names {
+			name, err := r.name()
+			if err != nil {
+				return "", err
+			}
+			names[i] = name
+		}
+		return strings.Join(names, " "), nil
+	}
+}
+
+// prefNameRDATA decodes a 16-bit preference followed by a domain name.
+func prefNameRDATA(r *rdataReader) (string, error) {
+	pref, err := r.u16()
+	if err != nil {
+		return "", err
+	}
+	name, err := r.name()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d %s", pref, name), nil
+}
+
+// stringsRDATA decodes a sequence of <character-string>s.
+func stringsRDATA(r *rdataReader) (string, error) {
+	var parts []string
+	for r.remaining() > 0 {
+		s, err := r.charString()
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// txtRDATA decodes TXT and SPF records.
+func txtRDATA(r *rdataReader) (string, error) {
+	return stringsRDATA(r)
+}
+
+// hinfoRDATA decodes the CPU and OS strings of an HINFO record.
+func hinfoRDATA(r *rdataReader) (string, error) {
+	cpu, err := r.charString()
+	if err != nil {
+		return "", err
+	}
+	osName, err := r.charString()
+	if err != nil {
+		return "", err
+	}
+	return cpu + " " + osName, nil
+}
+
+// base64RDATA renders opaque RDATA as base64.
+func base64RDATA(r *rdataReader) (string, error) {
+	return base64.StdEncoding.EncodeToString(r.rest()), nil
+}
+
+// srvRDATA decodes SRV priority, weight, port and target.
+func srvRDATA(r *rdataReader) (string, error) {
+	var v [3]uint16
+	for i := range v {
+		n, err := r.u16()
+		if err != nil {
+			return "", err
+		}
+		v[i] = n
+	}
+	target, err := r.name()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d %d %d %s", v[0], v[1], v[2], target), nil
+}
+
+// wksRDATA decodes a WKS address, protocol and port bitmap.
+func wksRDATA(r *rdataReader) (string, error) {
+	addr, err := r.bytes(4)
+	if err != nil {
+		return "", err
+	}
+	proto, err := r.u8()
+	if err != nil {
+		return "", err
+	}
+	parts := []string{net.IP(addr).String(), strconv.Itoa(int(proto))}
+	for i, b := range r.rest() {
+		for bit := 0; bit < 8; bit++ {
+			if b&(0x80>>bit) != 0 {
+				parts = append(parts, strconv.Itoa(i*8+bit))
+			}
+		}
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// nsapRDATA renders an NSAP address in its 0x-prefixed hex form.
+func nsapRDATA(r *rdataReader) (string, error) {
+	return "0x" + hex.EncodeToString(r.rest()), nil
+}
+
+// pxRDATA decodes PX preference, MAP822 and MAPX400 names.
+func pxRDATA(r *rdataReader) (string, error) {
+	pref, err := r.u16()
+	if err != nil {
+		return "", err
+	}
+	names, err := namesRDATA(2)(r)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d %s", pref, names), nil
+}
+
+// locRDATA decodes RFC 1876 location records.
+func locRDATA(r *rdataReader) (string, error) {
+	version, err := r.u8()
+	if err != nil || version != 0 {
+		return "", errors.New("unsupported LOC version")
+	}
+	var sizes [3]uint8
+	for i := range sizes {
+		if sizes[i], err = r.u8(); err != nil {
+			return "", err
+		}
+	}
+	var coords [3]uint32
+	for i := range coords {
+		if coords[i], err = r.u32(); err != nil {
+			return "", err
+		}
+	}
+	const equator = 1 << 31
+	lat := locAngle(int64(coords[0])-equator, "N", "S")
+	lon := locAngle(int64(coords[1])-equator, "E", "W")
+	alt := (float64(coords[2]) - 10000000) / 100
+	return fmt.Sprintf("%s %s %.2fm %sm %sm %sm", lat, lon, alt,
+		locSize(sizes[0]), locSize(sizes[1]), locSize(sizes[2])), nil
+}
+
+// locAngle formats a LOC latitude or longitude given in thousandths of
+// an arc second from the equator or prime meridian.
+func locAngle(thousandths int64, pos, neg string) string {
+	hemi := pos
+	if thousandths < 0 {
+		hemi = neg
+		thousandths = -thousandths
+	}
+	deg := thousandths / 3600000
+	thousandths %= 3600000
+	min := thousandths / 60000
+	thousandths %= 60000
+	return fmt.Sprintf("%d %d %.3f %s", deg, min, float64(thousandths)/1000, hemi)
+}
+
+// locSize formats a LOC size or precision byte, stored as a mantissa and
+// power-of-ten exponent of centimetres, in metres.
+func locSize(b uint8) string {
+	cm := float64(b>>4) * pow10(int(b&0x0F))
+	return strconv.FormatFloat(cm/100, 'f', -1, 64)
+}
+
+// pow10 returns 10 to the power n.
+func pow10(n int) float64 {
+	v := 1.0
+	for i := 0; i < n; i++ {
+		v *= 10
+	}
+	return v
+}
+
+// nxtRDATA decodes the next name and flat type bitmap of an NXT record.
+func nxtRDATA(r *rdataReader) (string, error) {
+	next, err := r.name()
+	if err != nil {
+		return "", err
+	}
+	parts := []string{next}
+	for i, b := range r.rest() {
+		for bit := 0; bit < 8; bit++ {
+			if b&(0x80>>bit) != 0 {
+				parts = append(parts, typeName(dnsmessage.Type(i*8+bit)))
+			}
+		}
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// naptrRDATA decodes NAPTR order, preference, flags, service, regexp and
+// replacement.
+func naptrRDATA(r *rdataReader) (string, error) {
+	order, err := r.u16()
+	if err != nil {
+		return "", err
+	}
+	pref, err := r.u16()
+	if err != nil {
+		return "", err
+	}
+	var fields [3]string
+	for i := range fields {
+		if fields[i], err = r.charString(); err != nil {
+			return "", err
+		}
+	}
+	replacement, err := r.name()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d %d %s %s %s %s", order, pref, fields[0], fields[1], fields[2], replacement), nil
+}
+
+// certRDATA decodes CERT type, key tag, algorithm and certificate.
+func certRDATA(r *rdataReader) (string, error) {
+	certType, err := r.u16()
+	if err != nil {
+		return "", err
+	}
+	keyTag, err := r.u16()
+	if err != nil {
+		return "", err
+	}
+	alg, err := r.u8()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d %d %d %s", certType, keyTag, alg, base64.StdEncoding.EncodeToString(r.rest())), nil
+}
+
+// aplRDATA decodes the address prefix list of an APL record (RFC 3123).
+func aplRDATA(r *rdataReader) (string, error) {
+	var items []string
+	for r.remaining() > 0 {
+		family, err := r.u16()
+		if err != nil {
+			return "", err
+		}
+		prefix, err := r.u8()
+		if err != nil {
+			return "", err
+		}
+		flags, err := r.u8()
+		if err != nil {
+			return "", err
+		}
+		addr, err := r.bytes(int(flags & 0x7F))
+		if err != nil {
+			return "", err
+		}
+		var ip net.IP
+		switch family {
+		case 1:
+			ip = make(net.IP, 4)
+		case 2:
+			ip = make(net.IP, 16)
+		default:
+			return "", errors.New("unknown APL address family")
+		}
+		copy(ip, addr)
+		item := fmt.Sprintf("%d:%s/%d", family, ip, prefix)
+		if flags&0x80 != 0 {
+			item = "!" + item
+		}
+		items = append(items, item)
+	}
+	return strings.Join(items, " "), nil
+}
+
+// dsRDATA decodes DS, CDS and DLV records.
+func dsRDATA(r *rdataReader) (string, error) {
+	keyTag, err := r.u16()
+	if err != nil {
+		return "", err
+	}
+	alg, err := r.u8()
+	if err != nil {
+		return "", err
+	}
+	digestType, err := r.u8()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d %d %d %s", keyTag, alg, digestType, strings.ToUpper(hex.EncodeToString(r.rest()))), nil
+}
+
+// sshfpRDATA decodes SSHFP algorithm, fingerprint type and fingerprint.
+func sshfpRDATA(r *rdataReader) (string, error) {
+	alg, err := r.u8()
+	if err != nil {
+		return "", err
+	}
+	fpType, err := r.u8()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d %d %s", alg, fpType, hex.EncodeToString(r.rest())), nil
+}
+
+// ipseckeyRDATA decodes IPSECKEY records, whose gateway form depends on
+// the gateway type.
+func ipseckeyRDATA(r *rdataReader) (string, error) {
+	precedence, err := r.u8()
+	if err != nil {
+		return "", err
+	}
+	gatewayType, err := r.u8()
+	if err != nil {
+		return "", err
+	}
+	alg, err := r.u8()
+	if err != nil {
+		return "", err
+	}
+	var gateway string
+	switch gatewayType {
+	case 0:
+		gateway = "."
+	case 1, 2:
+		size := 4
+		if gatewayType == 2 {
+			size = 16
+		}
+		addr, err := r.bytes(size)
+		if err != nil {
+			return "", err
+		}
+		gateway = net.IP(addr).String()
+	case 3:
+		if gateway, err = r.name(); err != nil {
+			return "", err
+		}
+	default:
+		return "", errors.New("unknown IPSECKEY gateway type")
+	}
+	key := base64.StdEncoding.EncodeToString(r.rest())
+	return fmt.Sprintf("%d %d %d %s %s", precedence, gatewayType, alg, gateway, key), nil
+}
+
+// rrsigRDATA decodes RRSIG and SIG records.
+func rrsigRDATA(r *rdataReader) (string, error) {
+	covered, err := r.u16()
+	if err != nil {
+		return "", err
+	}
+	alg, err := r.u8()
+	if err != nil {
+		return "", err
+	}
+	labels, err := r.u8()
+	if err != nil {
+		return "", err
+	}
+	var v [3]uint32
+	for i := range v {
+		if v[i], err = r.u32(); err != nil {
+			return "", err
+		}
+	}
+	keyTag, err := r.u16()
+	if err != nil {
+		return "", err
+	}
+	signer, err := r.name()
+	if err != nil {
+		return "", err
+	}
+	sig := base64.StdEncoding.EncodeToString(r.rest())
+	return fmt.Sprintf("%s %d %d %d %s %s %d %s %s", typeName(dnsmessage.Type(covered)), alg, labels, v[0],
+		sigTime(v[1]), sigTime(v[2]), keyTag, signer, sig), nil
+}
+
+// sigTime formats an RRSIG inception or expiration as YYYYMMDDHHmmSS.
+func sigTime(t uint32) string {
+	return time.Unix(int64(t), 0).UTC().Format("20060102150405")
+}
+
+// typeBitmaps decodes the window/bitmap type list used by NSEC and NSEC3.
+func typeBitmaps(b []byte) ([]string, error) {
+	var types []string
+	for len(b) > 0 {
+		if len(b) < 2 {
+			return nil, errShortRDATA
+		}
+		window, length := int(b[0]), int(b[1])
+		if length == 0 || length > 32 || len(b) < 2+length {
+			return nil, errShortRDATA
+		}
+		for i, bits := range b[2 : 2+length] {
+			for bit := 0; bit < 8; bit++ {
+				if bits&(0x80>>bit) != 0 {
+					types = append(types, typeName(dnsmessage.Type(window*256+i*8+bit)))
+				}
+			}
+		}
+		b = b[2+length:]
+	}
+	return types, nil
+}
+
+// nsecRDATA decodes the next name and type bitmaps of an NSEC record.
+func nsecRDATA(r *rdataReader) (string, error) {
+	next, err := r.name()
+	if err != nil {
+		return "", err
+	}
+	types, err := typeBitmaps(r.rest())
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(next + " " + strings.Join(types, " ")), nil
+}
+
+// dnskeyRDATA decodes DNSKEY, CDNSKEY and KEY records.
+func dnskeyRDATA(r *rdataReader) (string, error) {
+	flags, err := r.u16()
+	if err != nil {
+		return "", err
+	}
+	proto, err := r.u8()
+	if err != nil {
+		return "", err
+	}
+	alg, err := r.u8()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d %d %d %s", flags, proto, alg, base64.StdEncoding.EncodeToString(r.rest())), nil
+}
+
+// saltString renders an NSEC3 salt as hex, or "-" when empty.
+func saltString(salt []byte) string {
+	if len(salt) == 0 {
+		return "-"
+	}
+	return strings.ToUpper(hex.EncodeToString(salt))
+}
+
+// nsec3paramHeader decodes the hash parameters shared by NSEC3 and
+// NSEC3PARAM.
+func nsec3paramHeader(r *rdataReader) (string, error) {
+	alg, err := r.u8()
+	if err != nil {
+		return "", err
+	}
+	flags, err := r.u8()
+	if err != nil {
+		return "", err
+	}
+	iterations, err := r.u16()
+	if err != nil {
+		return "", err
+	}
+	saltLen, err := r.u8()
+	if err != nil {
+		return "", err
+	}
+	salt, err := r.bytes(int(saltLen))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d %d %d %s", alg, flags, iterations, saltString(salt)), nil
+}
+
+// nsec3paramRDATA decodes NSEC3PARAM records.
+func nsec3paramRDATA(r *rdataReader) (string, error) {
+	return nsec3paramHeader(r)
+}
+
+// nsec3RDATA decodes NSEC3 records, rendering the next hashed owner in
+// base32hex.
+func nsec3RDATA(r *rdataReader) (string, error) {
+	header, err := nsec3paramHeader(r)
+	if err != nil {
+		return "", err
+	}
+	hashLen, err := r.u8()
+	if err != nil {
+		return "", err
+	}
+	hash, err := r.bytes(int(hashLen))
+	if err != nil {
+		return "", err
+	}
+	types, err := typeBitmaps(r.rest())
+	if err != nil {
+		return "", err
+	}
+	next := base32.HexEncoding.WithPadding(base32.NoPadding).EncodeToString(hash)
+	return strings.TrimSpace(fmt.Sprintf("%s %s %s", header, next, strings.Join(types, " "))), nil
+}
+
+// tlsaRDATA decodes TLSA and SMIMEA records.
+func tlsaRDATA(r *rdataReader) (string, error) {
+	var v [3]uint8
+	for i := range v {
+		n, err := r.u8()
+		if err != nil {
+			return "", err
+		}
+		v[i] = n
+	}
+	return fmt.Sprintf("%d %d %d %s", v[0], v[1], v[2], hex.EncodeToString(r.rest())), nil
+}
+
+// hipRDATA decodes HIP records with any trailing rendezvous servers.
+func hipRDATA(r *rdataReader) (string, error) {
+	hitLen, err := r.u8()
+	if err != nil {
+		return "", err
+	}
+	alg, err := r.u8()
+	if err != nil {
+		return "", err
+	}
+	keyLen, err := r.u16()
+	if err != nil {
+		return "", err
+	}
+	hit, err := r.bytes(int(hitLen))
+	if err != nil {
+		return "", err
+	}
+	key, err := r.bytes(int(keyLen))
+	if err != nil {
+		return "", err
+	}
+	parts := []string{strconv.Itoa(int(alg)), strings.ToUpper(hex.EncodeToString(hit)), base64.StdEncoding.EncodeToString(key)}
+	for r.remaining() > 0 {
+		server, err := r.name()
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, server)
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// csyncRDATA decodes CSYNC serial, flags and type bitmaps.
+func csyncRDATA(r *rdataReader) (string, error) {
+	serial, err := r.u32()
+	if err != nil {
+		return "", err
+	}
+	flags, err := r.u16()
+	if err != nil {
+		return "", err
+	}
+	types, err := typeBitmaps(r.rest())
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(fmt.Sprintf("%d %d %s", serial, flags, strings.Join(types, " "))), nil
+}
+
+// zonemdRDATA decodes ZONEMD serial, scheme, hash algorithm and digest.
+func zonemdRDATA(r *rdataReader) (string, error) {
+	serial, err := r.u32()
+	if err != nil {
+		return "", err
+	}
+	scheme, err := r.u8()
+	if err != nil {
+		return "", err
+	}
+	alg, err := r.u8()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d %d %d %s", serial, scheme, alg, hex.EncodeToString(r.rest())), nil
+}
+
+var svcParamKeys = []string{"mandatory", "alpn", "no-default-alpn", "port", "ipv4hint", "ech", "ipv6hint"}
+
+// svcbRDATA decodes SVCB and HTTPS records (RFC 9460).
+func svcbRDATA(r *rdataReader) (string, error) {
+	priority, err := r.u16()
+	if err != nil {
+		return "", err
+	}
+	target, err := r.name()
+	if err != nil {
+		return "", err
+	}
+	parts := []string{strconv.Itoa(int(priority)), target}
+	for r.remaining() > 0 {
+		key, err := r.u16()
+		if err != nil {
+			return "", err
+		}
+		length, err := r.u16()
+		if err != nil {
+			return "", err
+		}
+		value, err := r.bytes(int(length))
+		if err != nil {
+			return "", err
+		}
+		name := fmt.Sprintf("key%d", key)
+		if int(key) < len(svcParamKeys) {
+			name = svcParamKeys[key]
+		}
+		if text := svcParamValue(key, value); text != "" {
+			name += "=" + text
+		}
+		parts = append(parts, name)
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// svcParamValue renders the value of a single SvcParam, or "" for keys
+// that take none.
+func svcParamValue(key uint16, value []byte) string {
+	switch key {
+	case 0:
+		var keys []string
+		for i := 0; i+1 < len(value); i += 2 {
+			k := binary.BigEndian.Uint16(value[i:])
+			if int(k) < len(svcParamKeys) {
+				keys = append(keys, svcParamKeys[k])
+			} else {
+				keys = append(keys, fmt.Sprintf("key%d", k))
+			}
+		}
+		return strings.Join(keys, ",")
+	case 1:
+		var alpns []string
+		for len(value) > 0 && int(value[0]) < len(value) {
+			alpns = append(alpns, string(value[1:1+value[0]]))
+			value = value[1+value[0]:]
+		}
+		return strings.Join(alpns, ",")
+	case 2:
+		return ""
+	case 3:
+		if len(value) == 2 {
+			return strconv.Itoa(int(binary.BigEndian.Uint16(value)))
+		}
+	case 4, 6:
+		size := 4
+		if key == 6 {
+			size = 16
+		}
+		var addrs []string
+		for i := 0; i+size <= len(value); i += size {
+			addrs = append(addrs, net.IP(value[i:i+size]).String())
+		}
+		return strings.Join(addrs, ",")
+	case 5:
+		return base64.StdEncoding.EncodeToString(value)
+	}
+	return quoteTXT(string(value))
+}
+
+// nidRDATA decodes NID and L64 records, which share a 64-bit locator.
+func nidRDATA(r *rdataReader) (string, error) {
+	pref, err := r.u16()
+	if err != nil {
+		return "", err
+	}
+	id, err := r.bytes(8)
+	if err != nil {
+		return "", err
+	}
+	h := hex.EncodeToString(id)
+	return fmt.Sprintf("%d %s:%s:%s:%s", pref, h[0:4], h[4:8], h[8:12], h[12:16]), nil
+}
+
+// l32RDATA decodes an L32 preference and 32-bit locator.
+func l32RDATA(r *rdataReader) (string, error) {
+	pref, err := r.u16()
+	if err != nil {
+		return "", err
+	}
+	addr, err := r.bytes(4)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d %s", pref, net.IP(addr)), nil
+}
+
+// euiRDATA returns a decoder for EUI48 or EUI64 records of size bytes.
+func euiRDATA(size int) func(*rdataReader) (string, error) {
+	return func(r *rdataReader) (string, error) {
+		b, err := r.bytes(size)
+		if err != nil {
+			return "", err
+		}
+		octets := make([]string, size)
+		for i, c := range b {
+			octets[i] = fmt.Sprintf("%02x", c)
+		}
+		return strings.Join(octets, "-"), nil
+	}
+}
+
+// uriRDATA decodes URI priority, weight and target.
+func uriRDATA(r *rdataReader) (string, error) {
+	priority, err := r.u16()
+	if err != nil {
+		return "", err
+	}
+	weight, err := r.u16()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d %d %s", priority, weight, quoteTXT(string(r.rest()))), nil
+}
+
+// caaRDATA decodes CAA flags, tag and value.
+func caaRDATA(r *rdataReader) (string, error) {
+	flags, err := r.u8()
+	if err != nil {
+		return "", err
+	}
+	tagLen, err := r.u8()
+	if err != nil {
+		return "", err
+	}
+	tag, err := r.bytes(int(tagLen))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d %s %s", flags, tag, quoteTXT(string(r.rest()))), nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// wireName encodes a dotted name as uncompressed wire-format labels.
+func wireName(name string) []byte {
+	var b []byte
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if label != "" {
+			b = append(b, byte(len(label)))
+			b = append(b, label...)
+		}
+	}
+	return append(b, 0)
+}
+
+func rdata(parts ...[]byte) []byte {
+	var b []byte
+	for _, p := range parts {
+		b = append(b, p...)
+	}
+	return b
+}
+
+func TestParseRawRDATA(t *testing.T) {
+	tests := []struct {
+		rrtype uint16
+		rdata  []byte
+		want   string
+	}{
+		{7, wireName("mail.example."), "mail.example."},
+		{13, []byte("\x03x86\x05Linux"), `"x86" "Linux"`},
+		{17, rdata(wireName("admin.example."), wireName("info.example.")), "admin.example. info.example."},
+		{18, rdata([]byte{0, 1}, wireName("afs.example.")), "1 afs.example."},
+		{29, []byte{0, 0x12, 0x16, 0x13, 0x8b, 0x0c, 0xfa, 0xc0, 0x7f, 0xf8, 0xff, 0x08, 0x00, 0x98, 0xa0, 0x44},
+			"51 30 0.000 N 0 7 39.000 W 25.00m 1m 10000m 10m"},
+		{33, rdata([]byte{0, 10, 0, 20, 0x01, 0xbb}, wireName("sip.example.")), "10 20 443 sip.example."},
+		{42, []byte{0, 1, 24, 3, 192, 0, 2, 0, 2, 64, 0x82, 0x20, 0x01}, "1:192.0.2.0/24 !2:2001::/64"},
+		{43, []byte{0x30, 0x39, 8, 2, 0xab, 0xcd}, "12345 8 2 ABCD"},
+		{44, []byte{1, 2, 0x01, 0x02}, "1 2 0102"},
+		{47, rdata(wireName("b.example."), []byte{0, 6, 0x62, 0, 0, 0, 0, 0x03}), "b.example. A NS SOA RRSIG NSEC"},
+		{51, []byte{1, 0, 0, 10, 2, 0xaa, 0xbb}, "1 0 10 AABB"},
+		{51, []byte{1, 0, 0, 0, 0}, "1 0 0 -"},
+		{52, []byte{3, 1, 1, 0xde, 0xad}, "3 1 1 dead"},
+		{65, rdata([]byte{0, 1, 0}, []byte{0, 1, 0, 6, 2, 'h', '2', 2, 'h', '3'}, []byte{0, 3, 0, 2, 0x01, 0xbb}, []byte{0, 4, 0, 4, 192, 0, 2, 1}),
+			"1 . alpn=h2,h3 port=443 ipv4hint=192.0.2.1"},
+		{104, []byte{0, 10, 0x00, 0x14, 0x4f, 0xff, 0xff, 0x20, 0xee, 0x64}, "10 0014:4fff:ff20:ee64"},
+		{108, []byte{0x00, 0x00, 0x5e, 0x00, 0x53, 0x2a}, "00-00-5e-00-53-2a"},
+		{256, rdata([]byte{0, 10, 0, 1}, []byte("https://example.com/")), `10 1 "https://example.com/"`},
+		{257, rdata([]byte{0, 5}, []byte("issueca.example")), `0 issue "ca.example"`},
+		{65280, []byte{0xca, 0xfe}, `\# 2 cafe`},
+		{105, []byte{0, 10, 192, 0, 2, 1, 0xff}, `\# 7 000ac0000201ff`},
+	}
+	for _, tt := range tests {
+		if got := parseRawRDATA(dnsmessage.Type(tt.rrtype), tt.rdata); got != tt.want {
+			t.Errorf("parseRawRDATA(%s, %x) = %q, want %q", typeName(dnsmessage.Type(tt.rrtype)), tt.rdata, got, tt.want)
+		}
+	}
+}
+
+func TestParseRawRDATAShort(t *testing.T) {
+	tests := []struct {
+		rrtype uint16
+		rdata  []byte
+	}{
+		{7, []byte("\x04mail")},
+		{18, []byte{0}},
+		{29, []byte{0, 0x12, 0x16, 0x13, 0x8b, 0x0c}},
+		{33, []byte{0, 10, 0, 20, 0x01}},
+		{42, []byte{0, 1, 24, 3, 192, 0}},
+		{43, []byte{0x30, 0x39, 8}},
+		{47, rdata(wireName("b.example."), []byte{0, 6, 0x62})},
+		{51, []byte{1, 0, 0, 10, 2, 0xaa}},
+		{55, []byte{4, 8, 0, 4, 0xde, 0xad}},
+		{65, rdata([]byte{0, 1, 0}, []byte{0, 3, 0, 2, 0x01})},
+		{104, []byte{0, 10, 0x00, 0x14, 0x4f, 0xff}},
+		{108, []byte{0x00, 0x00, 0x5e}},
+		{257, []byte{0, 5, 'i', 's'}},
+	}
+	for _, tt := range tests {
+		name := typeName(dnsmessage.Type(tt.rrtype))
+		if _, err := rdataParsers[tt.rrtype](&rdataReader{data: tt.rdata}); !errors.Is(err, errShortRDATA) {
+			t.Errorf("%s parser on %x: error = %v, want %v", name, tt.rdata, err, errShortRDATA)
+		}
+		if got, want := parseRawRDATA(dnsmessage.Type(tt.rrtype), tt.rdata), genericRDATA(tt.rdata); got != want {
+			t.Errorf("parseRawRDATA(%s, %x) = %q, want %q", name, tt.rdata, got, want)
+		}
+	}
+}
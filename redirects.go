@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// redirectWorkers bounds how many hosts have their redirects followed at once.
+const redirectWorkers = 10
+
+// followRedirects requests the root of host and records every hop until a
+// non-redirect response or maxHops is reached. HTTPS is tried first, plain
+// HTTP only when HTTPS is unreachable.
+func followRedirects(host string, maxHops int) []string {
+	var chain []string
+	for _, scheme := range []string{"https", "http"} {
+		current := scheme + "://" + host + "/"
+		chain = []string{current}
+		for hop := 0; hop < maxHops; hop++ {
+			resp, err := probeClient.Get(current)
+			if err != nil {
+				if hop == 0 {
+					chain = nil
+				}
+				break
+			}
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+			resp.Body.Close()
+
+			location := resp.Header.Get("Location")
+			if resp.StatusCode < 300 || resp.StatusCode >= 400 || location == "" {
+				break
+			}
+			next, err := resp.Request.URL.Parse(location)
+			if err != nil {
+				break
+			}
+			current = next.String()
+			chain = append(chain, current)
+		}
+		if chain != nil {
+			return chain
+		}
+	}
+	return nil
+}
+
+// followAllRedirects follows the redirect chain of every host in parallel
+// and prints chains that leave the starting URL, tagging hops that land
+// outside domain.
+func followAllRedirects(domain string, hosts []string, maxHops int) []DiscoveryRecord {
+	var mu sync.Mutex
+	var records []DiscoveryRecord
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, redirectWorkers)
+	for _, host := range hosts {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(host string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			chain := followRedirects(host, maxHops)
+			if len(chain) < 2 {
+				return
+			}
+			line := fmt.Sprintf(" - %s: %s", host, strings.Join(chain, " -> "))
+			if leavesDomain(chain, domain) {
+				line += " [CROSS-DOMAIN]"
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			fmt.Fprintln(status, line)
+			records = append(records, DiscoveryRecord{Name: host, RedirectChain: chain})
+		}(host)
+	}
+	wg.Wait()
+	return records
+}
+
+// leavesDomain reports whether any hop of chain points outside domain.
+func leavesDomain(chain []string, domain string) bool {
+	for _, hop := range chain {
+		u, err := url.Parse(hop)
+		if err != nil {
+			continue
+		}
+		host := strings.ToLower(u.Hostname())
+		if host != domain && !strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/noob6t5/sub_sniaX/subsniax"
+	"golang.org/x/time/rate"
+)
+
+// DNS-over-HTTPS endpoints for the built-in resolver presets.
+const (
+	googleDoHURL     = "https://dns.google/dns-query"
+	cloudflareDoHURL = "https://cloudflare-dns.com/dns-query"
+	quad9DoHURL      = "https://dns.quad9.net/dns-query"
+)
+
+// resolver is used for every lookup the tool makes. It defaults to the
+// system resolver and is replaced in main when -resolver is given.
+var resolver = net.DefaultResolver
+
+// requestRate is the -rps limit, limiter paces every DNS query and
+// connection the tool makes at that rate and is nil without one.
+// dialResolver is resolver before paceRequests wrapped it, it backs
+// newDialer and the library so that no operation waits on limiter twice.
+var (
+	requestRate  float64
+	limiter      *rate.Limiter
+	dialResolver *net.Resolver
+)
+
+func googleDoHResolver() *net.Resolver     { return newDoHResolver(googleDoHURL, dohClient) }
+func cloudflareDoHResolver() *net.Resolver { return newDoHResolver(cloudflareDoHURL, dohClient) }
+func quad9DoHResolver() *net.Resolver      { return newDoHResolver(quad9DoHURL, dohClient) }
+
+var resolverPresets = map[string]string{
+	"google":     googleDoHURL,
+	"cloudflare": cloudflareDoHURL,
+	"quad9":      quad9DoHURL,
+}
+
+// dohClient carries DoH queries unless a mode needs its own transport.
+var dohClient = &http.Client{Timeout: 10 * time.Second}
+
+// parseResolverURL turns a -resolver value into a resolver. It accepts
+// doh://<preset>, <preset>:// and full https:// DoH endpoint URLs.
+func parseResolverURL(u string) (*net.Resolver, error) {
+	endpoint, err := dohEndpoint(u)
+	if err != nil {
+		return nil, err
+	}
+	return newDoHResolver(endpoint, dohClient), nil
+}
+
+// resolverList collects the values of a repeated -resolver flag.
+type resolverList []string
+
+func (l *resolverList) String() string { return strings.Join(*l, ",") }
+
+func (l *resolverList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+// resolverServers holds the plain DNS servers given with -resolver, each
+// as host:port.
+var resolverServers []string
+
+// parseResolvers turns the -resolver values into a resolver. A single DoH
+// value is handled by parseResolverURL, anything else is a list of plain
+// DNS servers as host or host:port.
+func parseResolvers(values []string) (*net.Resolver, []string, error) {
+	if len(values) == 1 && strings.Contains(values[0], "://") {
+		r, err := parseResolverURL(values[0])
+		return r, nil, err
+	}
+	servers := make([]string, 0, len(values))
+	for _, v := range values {
+		if strings.Contains(v, "://") {
+			return nil, nil, fmt.Errorf("DoH resolver %q cannot be combined with other resolvers", v)
+		}
+		if _, _, err := net.SplitHostPort(v); err != nil {
+			v = net.JoinHostPort(strings.Trim(v, "[]"), "53")
+		}
+		servers = append(servers, v)
+	}
+	return subsniax.NewResolver(servers), servers, nil
+}
+
+// dohEndpoint returns the DoH endpoint URL a -resolver value refers to.
+func dohEndpoint(u string) (string, error) {
+	scheme, rest, ok := strings.Cut(u, "://")
+	if !ok {
+		return "", fmt.Errorf("invalid resolver %q: missing scheme", u)
+	}
+	switch scheme {
+	case "doh":
+		endpoint, ok := resolverPresets[strings.TrimSuffix(rest, "/")]
+		if !ok {
+			return "", fmt.Errorf("unknown DoH preset %q", rest)
+		}
+		return endpoint, nil
+	case "https":
+		return u, nil
+	}
+	if endpoint, ok := resolverPresets[scheme]; ok && (rest == "" || rest == "/") {
+		return endpoint, nil
+	}
+	return "", fmt.Errorf("unsupported resolver %q", u)
+}
+
+// newZeroTrustResolver returns a DoH resolver that authenticates to
+// endpoint with a client certificate, as Zero Trust DNS servers require.
+func newZeroTrustResolver(endpoint, certFile, keyFile string) (*net.Resolver, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load client certificate: %w", err)
+	}
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			Proxy:             http.ProxyFromEnvironment,
+			TLSClientConfig:   &tls.Config{Certificates: []tls.Certificate{cert}},
+			ForceAttemptHTTP2: true,
+		},
+	}
+	return newDoHResolver(endpoint, client), nil
+}
+
+// paceRequests limits the tool to rps DNS queries and connections per
+// second. Lookups through resolver wait once per nameserver exchange and
+// connections through newDialer once before connecting.
+func paceRequests(rps float64) {
+	limiter = rate.NewLimiter(rate.Limit(rps), 1)
+	dialResolver = resolver
+	resolver = subsniax.LimitResolver(resolver, limiter)
+}
+
+// unpacedResolver is the configured resolver without the -rps wait, for
+// callers that pace the connection instead.
+func unpacedResolver() *net.Resolver {
+	if dialResolver != nil {
+		return dialResolver
+	}
+	return resolver
+}
+
+// newDialer returns a dialer whose hostname lookups go through the
+// configured resolver. With -rps every connection it makes waits on
+// limiter first, its own lookup does not, and a Control set on the dialer
+// later still runs after the wait.
+func newDialer() *net.Dialer {
+	d := &net.Dialer{Resolver: unpacedResolver()}
+	if limiter != nil {
+		d.ControlContext = func(ctx context.Context, network, address string, c syscall.RawConn) error {
+			if err := limiter.Wait(ctx); err != nil {
+				return err
+			}
+			if d.Control != nil {
+				return d.Control(network, address, c)
+			}
+			return nil
+		}
+	}
+	return d
+}
+
+// newDoHResolver returns a resolver that sends every query to endpoint as an
+// RFC 8484 POST. The Go resolver treats the conn as a TCP stream, so the
+// conn only has to translate length-prefixed messages into HTTP requests.
+func newDoHResolver(endpoint string, client *http.Client) *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			return &dohConn{ctx: ctx, endpoint: endpoint, client: client}, nil
+		},
+	}
+}
+
+// dohConn is a net.Conn that carries DNS stream framing over HTTPS.
+type dohConn struct {
+	ctx      context.Context
+	endpoint string
+	client   *http.Client
+	query    bytes.Buffer
+	answer   bytes.Reader
+	deadline time.Time
+}
+
+func (c *dohConn) Write(b []byte) (int, error) {
+	return c.query.Write(b)
+}
+
+func (c *dohConn) Read(b []byte) (int, error) {
+	if c.answer.Len() == 0 && c.query.Len() > 0 {
+		if err := c.roundTrip(); err != nil {
+			return 0, err
+		}
+	}
+	return c.answer.Read(b)
+}
+
+func (c *dohConn) roundTrip() error {
+	framed := c.query.Bytes()
+	c.query.Reset()
+	if len(framed) < 2 {
+		return errors.New("short DNS query")
+	}
+
+	ctx := c.ctx
+	if !c.deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, c.deadline)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(framed[2:]))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("DoH server %s refused the query (403), the name may not be authorized", c.endpoint)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("DoH server %s returned %s", c.endpoint, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 65535))
+	if err != nil {
+		return err
+	}
+
+	msg := make([]byte, 2+len(body))
+	binary.BigEndian.PutUint16(msg, uint16(len(body)))
+	copy(msg[2:], body)
+	c.answer.Reset(msg)
+	return nil
+}
+
+func (c *dohConn) Close() error                       { return nil }
+func (c *dohConn) LocalAddr() net.Addr                { return dohAddr{} }
+func (c *dohConn) RemoteAddr() net.Addr               { return dohAddr{c.endpoint} }
+func (c *dohConn) SetDeadline(t time.Time) error      { c.deadline = t; return nil }
+func (c *dohConn) SetReadDeadline(t time.Time) error  { c.deadline = t; return nil }
+func (c *dohConn) SetWriteDeadline(t time.Time) error { return nil }
+
+type dohAddr struct{ endpoint string }
+
+func (a dohAddr) Network() string { return "https" }
+func (a dohAddr) String() string  { return a.endpoint }
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// reverseIP runs PTR lookups for ips on a pool of workers. The result is
+// keyed by the address string since net.IP cannot be a map key.
+func reverseIP(ips []net.IP, workers int) map[string][]string {
+	jobs := make(chan net.IP)
+	result := make(map[string][]string)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ip := range jobs {
+				names, err := resolver.LookupAddr(context.Background(), ip.String())
+				if err != nil || len(names) == 0 {
+					continue
+				}
+				for i := range names {
+					names[i] = strings.ToLower(strings.TrimSuffix(names[i], "."))
+				}
+				mu.Lock()
+				result[ip.String()] = names
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, ip := range ips {
+		jobs <- ip
+	}
+	close(jobs)
+	wg.Wait()
+	return result
+}
+
+// reverseDNSEnumerate resolves every host, looks up the PTR names of the
+// unique addresses and returns the ones that are new subdomains of domain.
+// Names outside domain are printed as signs of shared virtual hosting.
+func reverseDNSEnumerate(domain string, hosts []string, workers int) []string {
+	known := make(map[string]bool, len(hosts))
+	seenIP := make(map[string]bool)
+	var ips []net.IP
+	for _, host := range hosts {
+		known[host] = true
+		for _, ip := range lookupIPs(host) {
+			if !seenIP[ip.String()] {
+				seenIP[ip.String()] = true
+				ips = append(ips, ip)
+			}
+		}
+	}
+
+	var result []string
+	for ip, names := range reverseIP(ips, workers) {
+		for _, name := range names {
+			switch {
+			case known[name]:
+			case name == domain || strings.HasSuffix(name, "."+domain):
+				known[name] = true
+				result = append(result, name)
+				fmt.Fprintf(status, " - [REVERSE-DNS] %s (%s)\n", name, ip)
+			default:
+				fmt.Fprintf(status, " - [REVERSE-DNS] %s also points back to %s (virtual hosting)\n", ip, name)
+			}
+		}
+	}
+	return result
+}
+
+// reverseZoneName returns the in-addr.arpa or ip6.arpa zone covering
+// prefix, widened to the enclosing octet or nibble boundary.
+func reverseZoneName(prefix *net.IPNet) string {
+	ones, _ := prefix.Mask.Size()
+	var labels []string
+	if ip4 := prefix.IP.To4(); ip4 != nil {
+		for i := ones/8 - 1; i >= 0; i-- {
+			labels = append(labels, strconv.Itoa(int(ip4[i])))
+		}
+		return strings.Join(append(labels, "in-addr.arpa"), ".")
+	}
+	ip16 := prefix.IP.To16()
+	for i := ones/4 - 1; i >= 0; i-- {
+		nibble := ip16[i/2] >> 4
+		if i%2 == 1 {
+			nibble = ip16[i/2] & 0x0f
+		}
+		labels = append(labels, strconv.FormatUint(uint64(nibble), 16))
+	}
+	return strings.Join(append(labels, "ip6.arpa"), ".")
+}
+
+// reverseZoneAXFR attempts a zone transfer of the reverse zone covering
+// prefix from each of its nameservers and returns the PTR targets of the
+// first transfer that succeeds.
+func reverseZoneAXFR(prefix *net.IPNet) ([]string, error) {
+	zone := reverseZoneName(prefix)
+	nameServers, err := resolver.LookupNS(context.Background(), zone)
+	if err != nil {
+		return nil, fmt.Errorf("no nameservers for %s: %w", zone, err)
+	}
+	for _, ns := range nameServers {
+		records := attemptTransfer(context.Background(), zone, net.JoinHostPort(ns.Host, "53"), dnsmessage.TypeAXFR, int(dnsQueryTimeout.Milliseconds()))
+		var targets []string
+		for _, record := range records {
+			if record.Type == dnsmessage.TypePTR {
+				targets = append(targets, strings.ToLower(strings.TrimSuffix(record.Data, ".")))
+			}
+		}
+		if len(targets) > 0 {
+			return targets, nil
+		}
+	}
+	return nil, fmt.Errorf("AXFR of %s refused by all nameservers", zone)
+}
+
+// reverseZoneEnumerate tries to transfer the reverse zones of the /24 and
+// /48 networks the hosts resolve to and returns the new subdomains of
+// domain among the PTR records. These can be hosts with no forward entry.
+func reverseZoneEnumerate(domain string, hosts []string) []string {
+	known := make(map[string]bool, len(hosts))
+	prefixes := make(map[string]*net.IPNet)
+	for _, host := range hosts {
+		known[host] = true
+		for _, ip := range lookupIPs(host) {
+			mask := net.CIDRMask(48, 128)
+			if ip.To4() != nil {
+				mask = net.CIDRMask(24, 32)
+			}
+			prefix := &net.IPNet{IP: ip.Mask(mask), Mask: mask}
+			prefixes[prefix.String()] = prefix
+		}
+	}
+
+	var result []string
+	for _, prefix := range prefixes {
+		targets, err := reverseZoneAXFR(prefix)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(status, "Reverse zone %s of %s transferred\n", reverseZoneName(prefix), prefix)
+		for _, name := range targets {
+			if !known[name] && strings.HasSuffix(name, "."+domain) {
+				known[name] = true
+				result = append(result, name)
+				fmt.Fprintf(status, " - [REVERSE-AXFR] %s\n", name)
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// s3Workers bounds how many bucket names are checked at once.
+const s3Workers = 10
+
+// s3Words are combined with the domain name into bucket candidates, next
+// to the labels of the discovered subdomains.
+var s3Words = []string{
+	"backup", "backups", "assets", "static", "media", "uploads", "files", "data",
+	"logs", "images", "cdn", "public", "private", "dev", "staging", "prod", "www",
+}
+
+// s3BucketPattern matches the names S3 accepts for new buckets.
+var s3BucketPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+
+// S3BucketResult is a bucket that exists. Public is set when its listing
+// is readable anonymously, otherwise Status is what S3 answered instead.
+type S3BucketResult struct {
+	Bucket string
+	URL    string
+	Status int
+	Public bool
+}
+
+// s3BucketNames returns the bucket names worth trying for domain: the
+// domain itself, word.domain for every word, and the registrable label
+// joined with each word by a hyphen on either side.
+func s3BucketNames(domain string, commonWords []string) []string {
+	label := domain
+	if apex, err := publicsuffix.EffectiveTLDPlusOne(domain); err == nil {
+		suffix, _ := publicsuffix.PublicSuffix(apex)
+		label = strings.TrimSuffix(apex, "."+suffix)
+	}
+	candidates := []string{domain, strings.ReplaceAll(domain, ".", "-"), label}
+	for _, word := range commonWords {
+		word = strings.ToLower(word)
+		candidates = append(candidates, word+"."+domain, label+"-"+word, word+"-"+label)
+	}
+
+	seen := make(map[string]bool)
+	var names []string
+	for _, name := range candidates {
+		if !seen[name] && s3BucketPattern.MatchString(name) && !strings.Contains(name, "..") {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// checkS3Bucket requests bucket in virtual-hosted and then in path style.
+// 200 means a public listing, 403 a private bucket and a redirect a bucket
+// in another region, 404 on both means no such bucket.
+func checkS3Bucket(bucket string) (S3BucketResult, bool) {
+	for _, u := range []string{"https://" + bucket + ".s3.amazonaws.com/", "https://s3.amazonaws.com/" + bucket + "/"} {
+		resp, err := probeClient.Get(u)
+		if err != nil {
+			continue
+		}
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+		resp.Body.Close()
+		switch {
+		case resp.StatusCode == http.StatusOK:
+			return S3BucketResult{Bucket: bucket, URL: u, Status: resp.StatusCode, Public: true}, true
+		case resp.StatusCode == http.StatusForbidden, resp.StatusCode/100 == 3:
+			return S3BucketResult{Bucket: bucket, URL: u, Status: resp.StatusCode}, true
+		}
+	}
+	return S3BucketResult{}, false
+}
+
+// enumerateS3Buckets checks every bucket name s3BucketNames derives from
+// domain and commonWords and returns the buckets that exist, sorted by
+// name.
+func enumerateS3Buckets(domain string, commonWords []string) []S3BucketResult {
+	jobs := make(chan string)
+	var mu sync.Mutex
+	var result []S3BucketResult
+	var wg sync.WaitGroup
+	for i := 0; i < s3Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for bucket := range jobs {
+				if found, ok := checkS3Bucket(bucket); ok {
+					mu.Lock()
+					result = append(result, found)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, bucket := range s3BucketNames(domain, commonWords) {
+		jobs <- bucket
+	}
+	close(jobs)
+	wg.Wait()
+	sort.Slice(result, func(i, j int) bool { return result[i].Bucket < result[j].Bucket })
+	return result
+}
+
+// checkS3Buckets looks for buckets named after domain, its discovered
+// subdomains and s3Words, and prints the ones that exist.
+func checkS3Buckets(domain string, hosts []string) {
+	words := append([]string(nil), s3Words...)
+	for _, host := range hosts {
+		if label, ok := strings.CutSuffix(host, "."+domain); ok && !strings.Contains(label, ":") {
+			words = append(words, label)
+		}
+	}
+	for _, bucket := range enumerateS3Buckets(domain, words) {
+		if bucket.Public {
+			fmt.Fprintf(status, " - [S3-PUBLIC] %s is listable at %s\n", bucket.Bucket, bucket.URL)
+		} else {
+			fmt.Fprintf(status, " - [S3-PRIVATE] %s exists (%d at %s)\n", bucket.Bucket, bucket.Status, bucket.URL)
+		}
+	}
+}
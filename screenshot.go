@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+const (
+	screenshotTimeout = 30 * time.Second
+	webDialTimeout    = 5 * time.Second
+)
+
+var chromeBinaries = []string{"chromium", "chromium-browser", "google-chrome", "google-chrome-stable", "chrome"}
+
+var galleryTemplate = template.Must(template.New("gallery").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>sub_sniaX screenshots for {{.Domain}}</title>
+<style>
+body { font-family: sans-serif; background: #f4f4f4; }
+.shot { display: inline-block; margin: 8px; padding: 8px; background: #fff; vertical-align: top; }
+.shot img { width: 400px; border: 1px solid #ccc; }
+</style>
+</head>
+<body>
+<h1>{{.Domain}}</h1>
+{{range .Hosts}}<div class="shot"><a href="https://{{.}}/">{{.}}</a><br><a href="{{.}}.png"><img src="{{.}}.png" alt="{{.}}"></a></div>
+{{end}}</body>
+</html>
+`))
+
+// findChrome returns the path of the first Chrome or Chromium binary on PATH.
+func findChrome() (string, error) {
+	for _, name := range chromeBinaries {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, nil
+		}
+	}
+	return "", errors.New("no Chrome or Chromium binary found in PATH")
+}
+
+// captureScreenshot renders https://host/ with headless Chrome into path.
+func captureScreenshot(chrome, host, path string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), screenshotTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, chrome,
+		"--headless=new",
+		"--disable-gpu",
+		"--no-sandbox",
+		"--hide-scrollbars",
+		"--ignore-certificate-errors",
+		"--window-size=1280,800",
+		"--screenshot="+path,
+		"https://"+host+"/",
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, out)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return errors.New("chrome did not write a screenshot")
+	}
+	return nil
+}
+
+// screenshotHosts stores a {host}.png screenshot of every host in dir and
+// writes an index.html gallery of the ones that rendered.
+func screenshotHosts(domain string, hosts []string, dir string) {
+	chrome, err := findChrome()
+	if err != nil {
+		log.Printf("Skipping screenshots: %v\n", err)
+		return
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		log.Printf("Failed to create screenshot directory: %v\n", err)
+		return
+	}
+
+	var captured []string
+	for _, host := range hosts {
+		// Skip hosts without a web server rather than waiting on Chrome
+		dialer := newDialer()
+		dialer.Timeout = webDialTimeout
+		conn, err := dialer.Dial("tcp", net.JoinHostPort(host, "443"))
+		if err != nil {
+			continue
+		}
+		conn.Close()
+
+		path := filepath.Join(dir, host+".png")
+		if err := captureScreenshot(chrome, host, path); err != nil {
+			log.Printf("Failed to screenshot %s: %v\n", host, err)
+			continue
+		}
+		captured = append(captured, host)
+		fmt.Fprintf(status, " - [SCREENSHOT] %s\n", path)
+	}
+
+	index, err := os.Create(filepath.Join(dir, "index.html"))
+	if err != nil {
+		log.Printf("Failed to write screenshot gallery: %v\n", err)
+		return
+	}
+	defer index.Close()
+	err = galleryTemplate.Execute(index, struct {
+		Domain string
+		Hosts  []string
+	}{domain, captured})
+	if err != nil {
+		log.Printf("Failed to write screenshot gallery: %v\n", err)
+	}
+}
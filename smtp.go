@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/textproto"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var fqdnPattern = regexp.MustCompile(`(?i)\b(?:[a-z0-9](?:[a-z0-9-]*[a-z0-9])?\.)+[a-z]{2,63}\b`)
+
+// enumerateSMTPHeaders connects to mxHost on port 25 and returns every
+// hostname in its greeting banner and EHLO response. Both commonly name
+// the internal relay that answered rather than the public MX.
+func enumerateSMTPHeaders(mxHost string) ([]string, error) {
+	dialer := newDialer()
+	dialer.Timeout = smtpTimeout
+	conn, err := dialer.Dial("tcp", net.JoinHostPort(mxHost, "25"))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+
+	text := textproto.NewConn(conn)
+	_, banner, err := text.ReadResponse(220)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected SMTP greeting: %w", err)
+	}
+	if err := text.PrintfLine("EHLO sub-sniax.invalid"); err != nil {
+		return nil, err
+	}
+	_, ehlo, err := text.ReadResponse(250)
+	if err != nil {
+		return nil, fmt.Errorf("EHLO rejected: %w", err)
+	}
+	text.PrintfLine("QUIT")
+
+	seen := make(map[string]bool)
+	var names []string
+	for _, name := range fqdnPattern.FindAllString(banner+"\n"+ehlo, -1) {
+		name = strings.ToLower(name)
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// smtpEnumerate reads the SMTP banners of every MX host of domain and
+// returns the subdomains of domain named in them.
+func smtpEnumerate(domain string) []string {
+	mxs, err := resolver.LookupMX(context.Background(), domain)
+	if err != nil {
+		log.Printf("Failed to get MX records for %s: %v\n", domain, err)
+		return nil
+	}
+	var result []string
+	seen := make(map[string]bool)
+	for _, mx := range mxs {
+		host := strings.TrimSuffix(mx.Host, ".")
+		names, err := enumerateSMTPHeaders(host)
+		if err != nil {
+			log.Printf("SMTP banner grab on %s failed: %v\n", host, err)
+			continue
+		}
+		for _, name := range names {
+			if !seen[name] && (name == domain || strings.HasSuffix(name, "."+domain)) {
+				seen[name] = true
+				result = append(result, name)
+				fmt.Fprintf(status, " - [SMTP-HEADER] %s (from %s)\n", name, host)
+			}
+		}
+	}
+	return result
+}
+
+// defaultVRFYUsers are tried by -smtp-vrfy without -smtp-vrfy-wordlist.
+var defaultVRFYUsers = []string{
+	"admin", "administrator", "root", "postmaster", "hostmaster", "webmaster",
+	"info", "support", "sales", "abuse", "security", "noc", "test", "mail",
+}
+
+// smtpCommand connects to host on port 25, greets it with EHLO and sends
+// line, returning the reply code and text.
+func smtpCommand(host, line string) (int, string, error) {
+	dialer := newDialer()
+	dialer.Timeout = smtpTimeout
+	conn, err := dialer.Dial("tcp", net.JoinHostPort(host, "25"))
+	if err != nil {
+		return 0, "", err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+
+	text := textproto.NewConn(conn)
+	if _, _, err := text.ReadResponse(220); err != nil {
+		return 0, "", fmt.Errorf("unexpected SMTP greeting: %w", err)
+	}
+	if err := text.PrintfLine("EHLO sub-sniax.invalid"); err != nil {
+		return 0, "", err
+	}
+	if _, _, err := text.ReadResponse(250); err != nil {
+		return 0, "", fmt.Errorf("EHLO rejected: %w", err)
+	}
+	if err := text.PrintfLine("%s", line); err != nil {
+		return 0, "", err
+	}
+	// Any reply code is an answer, the callers tell them apart
+	code, reply, err := text.ReadResponse(0)
+	text.PrintfLine("QUIT")
+	return code, reply, err
+}
+
+// smtpVRFY asks the mail server host to verify username, sent as given so
+// callers add the @domain. It reports whether the server confirmed the
+// address (250 or 251) along with its reply. Servers with VRFY turned off
+// answer 252 or 502 for every address.
+func smtpVRFY(host, username string) (bool, string, error) {
+	code, reply, err := smtpCommand(host, "VRFY "+username)
+	if err != nil {
+		return false, "", err
+	}
+	return code == 250 || code == 251, reply, nil
+}
+
+// smtpEXPN asks host to expand the mailing list username, reporting
+// whether it did along with the members it listed.
+func smtpEXPN(host, username string) (bool, string, error) {
+	code, reply, err := smtpCommand(host, "EXPN "+username)
+	if err != nil {
+		return false, "", err
+	}
+	return code == 250, reply, nil
+}
+
+// checkSMTPVRFY tries every username@domain with VRFY and EXPN on each MX
+// host of domain, printing the confirmed addresses and flagging the
+// servers that answer either command.
+func checkSMTPVRFY(domain string, usernames []string) {
+	mxs, err := resolver.LookupMX(context.Background(), domain)
+	if err != nil {
+		log.Printf("Failed to get MX records for %s: %v\n", domain, err)
+		return
+	}
+	for _, mx := range mxs {
+		host := strings.TrimSuffix(mx.Host, ".")
+		var confirmed, expanded int
+		for _, username := range usernames {
+			address := username + "@" + domain
+			ok, reply, err := smtpVRFY(host, address)
+			if err != nil {
+				log.Printf("VRFY on %s failed: %v\n", host, err)
+				break
+			}
+			if ok {
+				confirmed++
+				fmt.Fprintf(status, " - [VRFY] %s confirmed by %s: %s\n", address, host, reply)
+			}
+			if ok, reply, err = smtpEXPN(host, address); err == nil && ok {
+				expanded++
+				fmt.Fprintf(status, " - [EXPN] %s expanded by %s: %s\n", address, host, strings.ReplaceAll(reply, "\n", ", "))
+			}
+		}
+		if confirmed > 0 {
+			fmt.Fprintf(status, " - [VRFY-ENABLED] %s confirmed %d of %d addresses\n", host, confirmed, len(usernames))
+		}
+		if expanded > 0 {
+			fmt.Fprintf(status, " - [EXPN-ENABLED] %s expanded %d addresses\n", host, expanded)
+		}
+	}
+}
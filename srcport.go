@@ -0,0 +1,38 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+)
+
+// srcPort is the local port zone transfer connections are made from, 0
+// lets the OS pick one.
+var srcPort int
+
+// srcPortMu serializes transfers while srcPort is fixed, since two sockets
+// cannot hold the same local port at once.
+var srcPortMu sync.Mutex
+
+// transferDialer returns the dialer for zone transfer connections, bound
+// to srcPort when one is set.
+func transferDialer() *net.Dialer {
+	dialer := newDialer()
+	if srcPort != 0 {
+		dialer.LocalAddr = &net.TCPAddr{Port: srcPort}
+		// Lets the port be bound again while the last connection is in TIME_WAIT
+		dialer.Control = reuseAddr
+	}
+	return dialer
+}
+
+// srcPortError explains a failure to bind srcPort, which for ports below
+// 1024 nearly always means missing privileges.
+func srcPortError(err error) error {
+	if srcPort != 0 && srcPort < 1024 && errors.Is(err, os.ErrPermission) {
+		return fmt.Errorf("binding source port %d needs root or CAP_NET_BIND_SERVICE (setcap cap_net_bind_service=+ep on the binary): %w", srcPort, err)
+	}
+	return err
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Stats counts, per domain, the subdomains each source found, the zone
+// transfers tried and the errors met during one scan.
+type Stats struct {
+	mu      sync.Mutex
+	domains map[string]*DomainStats
+	names   map[string]bool
+}
+
+// DomainStats is the tally of one domain.
+type DomainStats struct {
+	Sources       map[string]int `json:"sources"`
+	AXFRAttempts  int            `json:"axfr_attempts"`
+	AXFRSucceeded int            `json:"axfr_succeeded"`
+	Errors        int            `json:"errors"`
+}
+
+// scanSummary is the summary as written in -json mode.
+type scanSummary struct {
+	Domains       map[string]*DomainStats `json:"domains"`
+	Subdomains    int                     `json:"subdomains"`
+	AXFRAttempts  int                     `json:"axfr_attempts"`
+	AXFRSucceeded int                     `json:"axfr_succeeded"`
+	Errors        int                     `json:"errors"`
+	ElapsedMS     int64                   `json:"elapsed_ms"`
+}
+
+func newStats() *Stats {
+	return &Stats{domains: make(map[string]*DomainStats), names: make(map[string]bool)}
+}
+
+// domain returns the tally of domain, creating it. s.mu must be held.
+func (s *Stats) domain(domain string) *DomainStats {
+	d, ok := s.domains[domain]
+	if !ok {
+		d = &DomainStats{Sources: make(map[string]int)}
+		s.domains[domain] = d
+	}
+	return d
+}
+
+// startDomain adds domain to the report even if nothing is found for it.
+func (s *Stats) startDomain(domain string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.domain(domain)
+}
+
+// found counts a new subdomain of domain under the source that found it.
+func (s *Stats) found(domain string, finding Finding) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.domain(domain).Sources[finding.Source]++
+	s.names[strings.ToLower(strings.TrimSuffix(finding.Name, "."))] = true
+}
+
+// axfr counts a zone transfer tried for domain.
+func (s *Stats) axfr(domain string, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := s.domain(domain)
+	d.AXFRAttempts++
+	if ok {
+		d.AXFRSucceeded++
+	}
+}
+
+// failed counts an error met while enumerating domain.
+func (s *Stats) failed(domain string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.domain(domain).Errors++
+}
+
+// report prints the totals of the scan begun at start to stderr, then each
+// domain with the most subdomains first. In -json mode the summary also
+// goes to stderr as a JSON line, apart from the results on stdout.
+func (s *Stats) report(start time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	summary := scanSummary{Domains: s.domains, Subdomains: len(s.names), ElapsedMS: time.Since(start).Milliseconds()}
+	totals := make(map[string]int, len(s.domains))
+	domains := make([]string, 0, len(s.domains))
+	for domain, d := range s.domains {
+		for _, n := range d.Sources {
+			totals[domain] += n
+		}
+		summary.AXFRAttempts += d.AXFRAttempts
+		summary.AXFRSucceeded += d.AXFRSucceeded
+		summary.Errors += d.Errors
+		domains = append(domains, domain)
+	}
+	sort.Slice(domains, func(i, j int) bool {
+		if totals[domains[i]] != totals[domains[j]] {
+			return totals[domains[i]] > totals[domains[j]]
+		}
+		return domains[i] < domains[j]
+	})
+
+	fmt.Fprintf(os.Stderr, "\nSummary: %d unique subdomains across %d domains in %s, %d of %d zone transfers succeeded, %d errors\n",
+		summary.Subdomains, len(domains), time.Since(start).Round(time.Millisecond), summary.AXFRSucceeded, summary.AXFRAttempts, summary.Errors)
+	for _, domain := range domains {
+		d := s.domains[domain]
+		sources := make([]string, 0, len(d.Sources))
+		for source := range d.Sources {
+			sources = append(sources, source)
+		}
+		sort.Slice(sources, func(i, j int) bool {
+			if d.Sources[sources[i]] != d.Sources[sources[j]] {
+				return d.Sources[sources[i]] > d.Sources[sources[j]]
+			}
+			return sources[i] < sources[j]
+		})
+		for i, source := range sources {
+			sources[i] = fmt.Sprintf("%s %d", source, d.Sources[source])
+		}
+		line := fmt.Sprintf(" - %s %d", domain, totals[domain])
+		if len(sources) > 0 {
+			line += " (" + strings.Join(sources, ", ") + ")"
+		}
+		fmt.Fprintf(os.Stderr, "%s, AXFR %d/%d, %d errors\n", line, d.AXFRSucceeded, d.AXFRAttempts, d.Errors)
+	}
+	if jsonOutput {
+		if err := json.NewEncoder(os.Stderr).Encode(summary); err != nil {
+			log.Printf("Failed to encode summary: %v\n", err)
+		}
+	}
+}
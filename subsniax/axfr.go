@@ -0,0 +1,149 @@
+package subsniax
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// TypeIXFR is the incremental zone transfer query type, which dnsmessage
+// has no constant for.
+const TypeIXFR dnsmessage.Type = 251
+
+// Transfer sends a zone transfer style query of type qtype (AXFR, IXFR or
+// ANY) for domain over conn and reads the length-prefixed response
+// messages until the closing SOA or EOF. timeout is the idle time allowed
+// per message. The records read so far are returned along with any error,
+// a refused transfer is not an error and returns nothing.
+func Transfer(ctx context.Context, conn net.Conn, domain string, qtype dnsmessage.Type, class dnsmessage.Class, timeout time.Duration) ([]dnsmessage.Resource, error) {
+	// Unblock pending reads as soon as ctx is done
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	zone, err := dnsmessage.NewName(strings.TrimSuffix(domain, ".") + ".")
+	if err != nil {
+		return nil, err
+	}
+	msg := dnsmessage.Message{
+		Header:    dnsmessage.Header{RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: zone, Type: qtype, Class: class}},
+	}
+	if qtype == TypeIXFR {
+		// IXFR carries the client's SOA, serial 0 asks for the full zone
+		msg.Authorities = []dnsmessage.Resource{{
+			Header: dnsmessage.ResourceHeader{Name: zone, Type: dnsmessage.TypeSOA, Class: class},
+			Body:   &dnsmessage.SOAResource{NS: zone, MBox: zone},
+		}}
+	}
+	buf, err := msg.Pack()
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack request: %w", err)
+	}
+	framed := make([]byte, 2+len(buf))
+	binary.BigEndian.PutUint16(framed, uint16(len(buf)))
+	copy(framed[2:], buf)
+	if _, err := conn.Write(framed); err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	// A transfer streams messages until the zone's SOA comes round a
+	// second time. ANY and refused transfers fit in one.
+	var records []dnsmessage.Resource
+	var serial uint32
+	soas := 0
+	for ctx.Err() == nil {
+		conn.SetReadDeadline(time.Now().Add(timeout))
+		var length [2]byte
+		if _, err := io.ReadFull(conn, length[:]); err != nil {
+			if err == io.EOF || ctx.Err() != nil {
+				break
+			}
+			return records, fmt.Errorf("error reading response: %w", err)
+		}
+		resBuf := make([]byte, binary.BigEndian.Uint16(length[:]))
+		if _, err := io.ReadFull(conn, resBuf); err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			return records, fmt.Errorf("truncated response: %w", err)
+		}
+
+		var resp dnsmessage.Message
+		if err := resp.Unpack(resBuf); err != nil {
+			return records, fmt.Errorf("failed to unpack response: %w", err)
+		}
+		if resp.RCode != dnsmessage.RCodeSuccess {
+			break
+		}
+
+		done := false
+		for i, answer := range resp.Answers {
+			if soa, ok := answer.Body.(*dnsmessage.SOAResource); ok {
+				soas++
+				if soas == 1 {
+					serial = soa.Serial
+				} else if soa.Serial == serial && i == len(resp.Answers)-1 {
+					// The closing SOA repeats the opening one
+					done = true
+					break
+				}
+			}
+			records = append(records, answer)
+		}
+		if done || soas == 0 || (qtype != dnsmessage.TypeAXFR && qtype != TypeIXFR) {
+			break
+		}
+	}
+	return records, nil
+}
+
+// AttemptAXFR requests a full zone transfer of domain from the nameserver
+// at server (host or host:port) and returns the address and alias records
+// as findings. Options.Delay bounds the connect as well as every wait for
+// a message of the transfer.
+func AttemptAXFR(ctx context.Context, domain, server string, opts Options) ([]Finding, error) {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	timeout := orDefault(opts.Delay, DefaultDelay)
+	conn, err := opts.dialer(timeout).DialContext(ctx, "tcp", server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	records, err := Transfer(ctx, conn, domain, dnsmessage.TypeAXFR, dnsmessage.ClassINET, timeout)
+
+	var findings []Finding
+	index := make(map[string]int)
+	for _, rr := range records {
+		var addr, rtype string
+		switch body := rr.Body.(type) {
+		case *dnsmessage.AResource:
+			addr, rtype = net.IP(body.A[:]).String(), "A"
+		case *dnsmessage.CNAMEResource:
+			rtype = "CNAME"
+		default:
+			continue
+		}
+		name := strings.TrimSuffix(rr.Header.Name.String(), ".")
+		i, ok := index[name]
+		if !ok {
+			i = len(findings)
+			index[name] = i
+			findings = append(findings, Finding{Name: name, Source: "axfr", RecordType: rtype})
+		}
+		if addr != "" {
+			findings[i].Addrs = append(findings[i].Addrs, addr)
+		}
+	}
+	for _, f := range findings {
+		opts.progress(f)
+	}
+	return findings, err
+}
@@ -0,0 +1,84 @@
+package subsniax
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// serveTransfer answers the request read from conn with msgs, one framed
+// message each, and then closes conn unless stall is set.
+func serveTransfer(t *testing.T, conn net.Conn, msgs []dnsmessage.Message, stall bool) {
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := io.ReadFull(conn, make([]byte, binary.BigEndian.Uint16(length[:]))); err != nil {
+		t.Error(err)
+		return
+	}
+	for _, msg := range msgs {
+		buf, err := msg.Pack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		conn.Write(binary.BigEndian.AppendUint16(nil, uint16(len(buf))))
+		conn.Write(buf)
+	}
+	if !stall {
+		conn.Close()
+	}
+}
+
+func TestTransfer(t *testing.T) {
+	zone := dnsmessage.MustNewName("example.com.")
+	www := dnsmessage.MustNewName("www.example.com.")
+	header := func(name dnsmessage.Name, rtype dnsmessage.Type) dnsmessage.ResourceHeader {
+		return dnsmessage.ResourceHeader{Name: name, Type: rtype, Class: dnsmessage.ClassINET, TTL: 300}
+	}
+	soa := dnsmessage.Resource{Header: header(zone, dnsmessage.TypeSOA), Body: &dnsmessage.SOAResource{NS: zone, MBox: zone, Serial: 7}}
+	a := dnsmessage.Resource{Header: header(www, dnsmessage.TypeA), Body: &dnsmessage.AResource{A: [4]byte{192, 0, 2, 1}}}
+	cname := dnsmessage.Resource{Header: header(dnsmessage.MustNewName("ftp.example.com."), dnsmessage.TypeCNAME), Body: &dnsmessage.CNAMEResource{CNAME: www}}
+	answer := func(rrs ...dnsmessage.Resource) dnsmessage.Message {
+		return dnsmessage.Message{Header: dnsmessage.Header{Response: true}, Answers: rrs}
+	}
+
+	tests := []struct {
+		name    string
+		msgs    []dnsmessage.Message
+		stall   bool
+		want    int
+		wantErr bool
+	}{
+		{"two messages", []dnsmessage.Message{answer(soa, a), answer(cname, soa)}, false, 3, false},
+		{"one message", []dnsmessage.Message{answer(soa, a, cname, soa)}, true, 3, false},
+		{"refused", []dnsmessage.Message{{Header: dnsmessage.Header{Response: true, RCode: dnsmessage.RCodeRefused}}}, true, 0, false},
+		{"closed early", []dnsmessage.Message{answer(soa, a)}, false, 2, false},
+		{"idle", []dnsmessage.Message{answer(soa, a)}, true, 2, true},
+	}
+	for _, tt := range tests {
+		client, server := net.Pipe()
+		go serveTransfer(t, server, tt.msgs, tt.stall)
+		start := time.Now()
+		records, err := Transfer(context.Background(), client, "example.com", dnsmessage.TypeAXFR, dnsmessage.ClassINET, 100*time.Millisecond)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Transfer error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+		if len(records) != tt.want {
+			t.Errorf("%s: Transfer returned %d records, want %d", tt.name, len(records), tt.want)
+		}
+		// The idle timeout is the one given, not raised to DefaultDelay
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("%s: Transfer took %v with a 100ms idle timeout", tt.name, elapsed)
+		}
+		client.Close()
+		server.Close()
+	}
+}
@@ -0,0 +1,76 @@
+package subsniax
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Modes pick how wordlist candidates are tested.
+const (
+	ModeSNI  = "sni"  // TLS handshake on the SNI ports
+	ModeDNS  = "dns"  // A/AAAA lookup
+	ModeBoth = "both" // lookup and handshake, each reporting its own hits
+)
+
+// DNSEnumerate resolves every label of the wordlist under domain, Threads
+// names at a time, and returns a finding with the IPv4 and IPv6 addresses
+// of each name that resolves. Unlike SNIEnumerate it finds hosts that
+// serve no TLS at all. Names that resolve only to Options.Wildcard are
+// left out. Once ctx is done no new names are resolved.
+func DNSEnumerate(ctx context.Context, domain string, opts Options) []Finding {
+	opts.Resolver = opts.baseResolver()
+	wordlist := opts.Wordlist
+	if len(wordlist) == 0 {
+		wordlist = DefaultWordlist
+	}
+
+	candidates := make(chan string)
+	hits := make(chan Finding)
+	var wg sync.WaitGroup
+	for range max(orDefault(opts.Threads, DefaultThreads), 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range candidates {
+				if f, ok := ResolveName(ctx, name, opts); ok {
+					hits <- f
+				}
+			}
+		}()
+	}
+	go func() {
+		defer close(hits)
+		defer wg.Wait()
+		defer close(candidates)
+		seen := make(map[string]bool, len(wordlist))
+		for _, label := range wordlist {
+			if seen[label] {
+				continue
+			}
+			seen[label] = true
+			select {
+			case candidates <- fmt.Sprintf("%s.%s", label, domain):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var result []Finding
+	for f := range hits {
+		opts.progress(f)
+		result = append(result, f)
+	}
+	return result
+}
+
+// ResolveName looks up a single brute force candidate the way DNSEnumerate
+// does, returning its finding when it resolves to more than the wildcard.
+func ResolveName(ctx context.Context, name string, opts Options) (Finding, bool) {
+	addrs, err := opts.resolver().LookupHost(ctx, name)
+	if err != nil || len(addrs) == 0 || onlyWildcard(addrs, opts.Wildcard) {
+		return Finding{}, false
+	}
+	return Finding{Name: name, Source: "dns", Addrs: addrs}, true
+}
@@ -0,0 +1,33 @@
+package subsniax
+
+import (
+	"context"
+	"strings"
+)
+
+// CNAMEChain follows the CNAME chain starting at domain and returns every
+// name on it after domain itself. Loops end the chain.
+func CNAMEChain(ctx context.Context, domain string, opts Options) ([]Finding, error) {
+	r := opts.resolver()
+	var result []Finding
+	seen := make(map[string]bool)
+	name := strings.TrimSuffix(domain, ".")
+	for {
+		cname, err := r.LookupCNAME(ctx, name)
+		if err != nil {
+			if len(result) == 0 {
+				return nil, err
+			}
+			return result, nil
+		}
+		cname = strings.TrimSuffix(cname, ".")
+		if cname == name || seen[cname] {
+			return result, nil
+		}
+		seen[cname] = true
+		f := Finding{Name: cname, Source: "cname", RecordType: "CNAME"}
+		opts.progress(f)
+		result = append(result, f)
+		name = cname
+	}
+}
@@ -0,0 +1,5 @@
+// Package subsniax is the enumeration core of sub_sniaX: zone transfers,
+// CNAME chains and SNI probing, usable without the command line tool. The
+// functions here never print or touch files, progress is reported through
+// Options.Progress.
+package subsniax
@@ -0,0 +1,75 @@
+package subsniax
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+)
+
+// EnumerateSubdomains runs the core enumeration of domain: a zone transfer
+// from each of its nameservers until one succeeds, its CNAME chain and
+// testing of the wordlist as Options.Mode says, skipping names a wildcard
+// record answers for, plus the names found on the certificates seen along
+// the way. Every name is returned once, the first method to find it is its
+// source. The error is only set when nothing could be tried at all.
+func EnumerateSubdomains(ctx context.Context, domain string, opts Options) ([]Finding, error) {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	opts.Resolver = opts.baseResolver()
+	var mu sync.Mutex
+	seen := make(map[string]bool)
+	var result []Finding
+	add := func(findings []Finding) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, f := range findings {
+			key := strings.ToLower(f.Name)
+			if !seen[key] {
+				seen[key] = true
+				result = append(result, f)
+			}
+		}
+	}
+
+	nameServers, nsErr := opts.resolver().LookupNS(ctx, domain)
+	axfrCtx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	for _, ns := range nameServers {
+		wg.Add(1)
+		go func(host string) {
+			defer wg.Done()
+			findings, err := AttemptAXFR(axfrCtx, domain, host, opts)
+			if err == nil && len(findings) > 0 {
+				cancel()
+			}
+			add(findings)
+		}(ns.Host)
+	}
+	wg.Wait()
+	cancel()
+
+	chain, cnameErr := CNAMEChain(ctx, domain, opts)
+	add(chain)
+
+	if !opts.NoWildcardFilter && opts.Wildcard == nil {
+		opts.Wildcard = DetectWildcard(ctx, domain, opts)
+	}
+	if opts.Mode == ModeDNS || opts.Mode == ModeBoth {
+		add(DNSEnumerate(ctx, domain, opts))
+	}
+	if opts.Mode != ModeDNS {
+		endpoints, certNames := SNIEnumerate(ctx, domain, opts)
+		add(endpoints)
+		certFindings := make([]Finding, len(certNames))
+		for i, name := range certNames {
+			certFindings[i] = Finding{Name: name, Source: "sni-cert"}
+			opts.progress(certFindings[i])
+		}
+		add(certFindings)
+	}
+
+	if len(result) == 0 && nsErr != nil && cnameErr != nil {
+		return nil, errors.Join(nsErr, cnameErr)
+	}
+	return result, ctx.Err()
+}
@@ -0,0 +1,151 @@
+package subsniax
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Defaults applied to zero Options fields.
+const (
+	DefaultThreads    = 20
+	DefaultTLSTimeout = 5 * time.Second
+	DefaultDelay      = 5 * time.Second
+)
+
+// Finding is a subdomain together with the method that found it, the
+// type of record it came from and any addresses already known for it.
+// Name carries a :port for endpoints found on a port other than 443.
+type Finding struct {
+	Name       string
+	Source     string
+	RecordType string
+	Addrs      []string
+}
+
+// Options configures an enumeration. The zero value probes the built-in
+// wordlist on port 443 through the system resolver.
+type Options struct {
+	// Delay is how long a zone transfer may go without a message. A
+	// configured value is used as is, shorter than DefaultDelay included,
+	// and DefaultDelay only applies when it is zero.
+	Delay time.Duration
+	// Threads is the number of names probed at once during SNI enumeration.
+	Threads int
+	// Resolvers are DNS servers as host:port, used round-robin. Resolver
+	// takes precedence when set. The enumeration functions build the
+	// round-robin resolver once per call, callers of ResolveName,
+	// MatchesWildcard or DetectWildcard in a loop should set Resolver to
+	// NewResolver(servers) instead so the rotation carries across calls.
+	Resolvers []string
+	Resolver  *net.Resolver
+	// Wordlist holds the labels probed under the domain, DefaultWordlist
+	// when empty.
+	Wordlist []string
+	// Ports are the ports probed for TLS, 443 when empty.
+	Ports      []string
+	TLSTimeout time.Duration
+	// Wildcard holds the addresses of a catch-all record, as returned by
+	// DetectWildcard. SNIEnumerate skips names resolving only to them.
+	Wildcard []string
+	// NoWildcardFilter stops EnumerateSubdomains from detecting Wildcard.
+	NoWildcardFilter bool
+	// Mode is ModeSNI, ModeDNS or ModeBoth and picks how EnumerateSubdomains
+	// tests the wordlist, ModeSNI when empty.
+	Mode string
+	// Limiter, when set, paces the enumeration. Every lookup through
+	// Resolver and every connection takes one token, a connection to a
+	// hostname does not take another for resolving it. Resolver should
+	// not be wrapped with LimitResolver as well.
+	Limiter *rate.Limiter
+	// Progress, when set, is called with every finding as it is made,
+	// possibly from several goroutines at once.
+	Progress func(Finding)
+}
+
+// baseResolver is Resolver, or the round-robin resolver built once from
+// Resolvers, or the system resolver, without Limiter.
+func (o *Options) baseResolver() *net.Resolver {
+	switch {
+	case o.Resolver != nil:
+		return o.Resolver
+	case len(o.Resolvers) > 0:
+		o.Resolver = NewResolver(o.Resolvers)
+		return o.Resolver
+	}
+	return net.DefaultResolver
+}
+
+// resolver is the resolver lookups go through, paced by Limiter when set.
+func (o *Options) resolver() *net.Resolver {
+	if o.Limiter != nil {
+		return LimitResolver(o.baseResolver(), o.Limiter)
+	}
+	return o.baseResolver()
+}
+
+// dialer waits on Limiter only for the connection itself. It resolves
+// through baseResolver, so dialing a hostname costs one token.
+func (o *Options) dialer(timeout time.Duration) *net.Dialer {
+	d := &net.Dialer{Resolver: o.baseResolver(), Timeout: timeout}
+	if limiter := o.Limiter; limiter != nil {
+		d.ControlContext = func(ctx context.Context, network, address string, c syscall.RawConn) error {
+			return limiter.Wait(ctx)
+		}
+	}
+	return d
+}
+
+func (o *Options) progress(f Finding) {
+	if o.Progress != nil {
+		o.Progress(f)
+	}
+}
+
+func orDefault[T comparable](v, def T) T {
+	var zero T
+	if v == zero {
+		return def
+	}
+	return v
+}
+
+// NewResolver returns a resolver that sends each query to the next of
+// servers (host:port) in turn. Server names are resolved by the system
+// resolver.
+func NewResolver(servers []string) *net.Resolver {
+	var next atomic.Uint32
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			server := servers[int(next.Add(1)-1)%len(servers)]
+			var d net.Dialer
+			return d.DialContext(ctx, network, server)
+		},
+	}
+}
+
+// LimitResolver returns a resolver that waits on l before every connection
+// r makes to a nameserver, so each query is paced. A resolver without its
+// own Dial is switched to the Go resolver to make that possible.
+func LimitResolver(r *net.Resolver, l *rate.Limiter) *net.Resolver {
+	dial := r.Dial
+	if dial == nil {
+		var d net.Dialer
+		dial = d.DialContext
+	}
+	return &net.Resolver{
+		PreferGo:     true,
+		StrictErrors: r.StrictErrors,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			if err := l.Wait(ctx); err != nil {
+				return nil, err
+			}
+			return dial(ctx, network, address)
+		},
+	}
+}
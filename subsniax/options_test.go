@@ -0,0 +1,29 @@
+package subsniax
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestDialerTakesOneToken(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	limiter := rate.NewLimiter(rate.Every(time.Hour), 3)
+	opts := Options{Limiter: limiter}
+	conn, err := opts.dialer(time.Second).DialContext(context.Background(), "tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	if tokens := limiter.Tokens(); tokens < 1.5 || tokens > 2.5 {
+		t.Errorf("dial left %.1f of 3 tokens, want 2", tokens)
+	}
+}
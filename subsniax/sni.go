@@ -0,0 +1,168 @@
+package subsniax
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+// DefaultWordlist is probed when Options.Wordlist is empty.
+var DefaultWordlist = []string{
+	"www", "mail", "ftp", "webmail", "smtp", "portal", "vpn", "api", "dev", "test",
+	"staging", "beta", "alpha", "dev-api", "sandbox", "preprod", "prod", "uat", "qa", "demo",
+	"auth", "login", "register", "signup", "accounts", "user", "profile", "admin", "adminpanel",
+	"help", "support", "docs", "documentation", "contact", "knowledgebase", "kb", "faq",
+	"blog", "news", "media", "static", "images", "img", "cdn", "video", "assets", "resources",
+	"shop", "store", "cart", "checkout", "order", "payments", "billing", "invoice", "pay",
+	"analytics", "track", "tracking", "stats", "metrics", "data", "insights", "reports",
+	"status", "monitor", "dashboard", "gateway", "node", "cdn", "proxy", "edge", "backup",
+	"community", "forum", "discuss", "discussion", "social", "events", "meetup", "groups",
+	"internal", "devtools", "tools", "config", "settings", "configurations",
+	"developers", "developer", "api-docs", "api-portal", "graphql", "rest",
+	"marketing", "promo", "offers", "campaign", "landing", "sales",
+	"client", "userportal", "account", "my", "myaccount", "customer", "members", "portal",
+	"app", "test1", "test2", "api-staging", "dashboard", "console", "manage", "sso", "single-sign-on",
+	"backup", "service", "sync",
+}
+
+// SNIEnumerate probes every label of the wordlist under domain on every
+// port, Threads names at a time. It returns a finding per name:port
+// endpoint that completed a TLS handshake, with port 443 left implicit,
+// and the other names under domain that the certificates of those
+// endpoints cover. Names that resolve only to Options.Wildcard are not
+// probed. Once ctx is done no new names are probed.
+func SNIEnumerate(ctx context.Context, domain string, opts Options) ([]Finding, []string) {
+	opts.Resolver = opts.baseResolver()
+	wordlist := opts.Wordlist
+	if len(wordlist) == 0 {
+		wordlist = DefaultWordlist
+	}
+	ports := opts.Ports
+	if len(ports) == 0 {
+		ports = []string{"443"}
+	}
+
+	type hit struct {
+		addr string
+		open []string
+		sans []string
+	}
+	candidates := make(chan string)
+	hits := make(chan hit)
+	var wg sync.WaitGroup
+	for range max(orDefault(opts.Threads, DefaultThreads), 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for addr := range candidates {
+				if MatchesWildcard(ctx, addr, opts.Wildcard, opts) {
+					continue
+				}
+				if open, sans := probePorts(ctx, addr, ports, opts); len(open) > 0 {
+					hits <- hit{addr, open, sans}
+				}
+			}
+		}()
+	}
+	go func() {
+		defer close(hits)
+		defer wg.Wait()
+		defer close(candidates)
+		seen := make(map[string]bool, len(wordlist))
+		for _, label := range wordlist {
+			if seen[label] {
+				continue
+			}
+			seen[label] = true
+			select {
+			case candidates <- fmt.Sprintf("%s.%s", label, domain):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var names, sans []string
+	var endpoints []Finding
+	for h := range hits {
+		names = append(names, h.addr)
+		sans = append(sans, h.sans...)
+		for _, port := range h.open {
+			endpoint := h.addr
+			if port != "443" {
+				endpoint = net.JoinHostPort(h.addr, port)
+			}
+			f := Finding{Name: endpoint, Source: "sni"}
+			opts.progress(f)
+			endpoints = append(endpoints, f)
+		}
+	}
+	return endpoints, CertNames(sans, domain, names)
+}
+
+// CertNames reduces certificate names to the distinct names under domain
+// that are not in known. A wildcard stands for its base name.
+func CertNames(sans []string, domain string, known []string) []string {
+	seen := make(map[string]bool, len(known))
+	for _, name := range known {
+		seen[strings.ToLower(name)] = true
+	}
+	var result []string
+	for _, san := range sans {
+		name := strings.TrimPrefix(strings.ToLower(strings.TrimSuffix(san, ".")), "*.")
+		if seen[name] || (name != domain && !strings.HasSuffix(name, "."+domain)) {
+			continue
+		}
+		seen[name] = true
+		result = append(result, name)
+	}
+	return result
+}
+
+// probePorts probes addr on all ports at once and returns the ones that
+// complete a TLS handshake, in the order given, along with the DNS names
+// of the certificates served.
+func probePorts(ctx context.Context, addr string, ports []string, opts Options) ([]string, []string) {
+	ok := make([]bool, len(ports))
+	names := make([][]string, len(ports))
+	var wg sync.WaitGroup
+	for i, port := range ports {
+		wg.Add(1)
+		go func(i int, port string) {
+			defer wg.Done()
+			names[i], ok[i] = ProbeSNI(ctx, addr, port, opts)
+		}(i, port)
+	}
+	wg.Wait()
+
+	var open, sans []string
+	for i, port := range ports {
+		if ok[i] {
+			open = append(open, port)
+			sans = append(sans, names[i]...)
+		}
+	}
+	return open, sans
+}
+
+// ProbeSNI reports whether addr completes a TLS handshake on port within
+// Options.TLSTimeout and returns the DNS names of the leaf certificate it
+// served.
+func ProbeSNI(ctx context.Context, addr, port string, opts Options) ([]string, bool) {
+	dialer := &tls.Dialer{
+		NetDialer: opts.dialer(orDefault(opts.TLSTimeout, DefaultTLSTimeout)),
+		Config:    &tls.Config{ServerName: addr, InsecureSkipVerify: true},
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(addr, port))
+	if err != nil {
+		return nil, false
+	}
+	defer conn.Close()
+	if certs := conn.(*tls.Conn).ConnectionState().PeerCertificates; len(certs) > 0 {
+		return certs[0].DNSNames, true
+	}
+	return nil, true
+}
@@ -0,0 +1,61 @@
+package subsniax
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"slices"
+)
+
+// wildcardProbes is how many random labels DetectWildcard resolves. A
+// catch-all that rotates through a pool shows more of it on every probe.
+const wildcardProbes = 3
+
+// DetectWildcard resolves a few random labels that cannot exist under
+// domain and returns the sorted union of the addresses they resolved to,
+// nil when the domain has no wildcard record.
+func DetectWildcard(ctx context.Context, domain string, opts Options) []string {
+	var addrs []string
+	for range wildcardProbes {
+		name := fmt.Sprintf("sniax-%012x.%s", rand.Int63n(1<<48), domain)
+		ips, err := opts.resolver().LookupHost(ctx, name)
+		if err != nil {
+			continue
+		}
+		for _, ip := range ips {
+			if !slices.Contains(addrs, ip) {
+				addrs = append(addrs, ip)
+			}
+		}
+	}
+	slices.Sort(addrs)
+	return addrs
+}
+
+// MatchesWildcard reports whether name resolves to nothing but addresses
+// of the wildcard set, which makes it indistinguishable from a name that
+// does not exist. Names that fail to resolve never match.
+func MatchesWildcard(ctx context.Context, name string, wildcard []string, opts Options) bool {
+	if len(wildcard) == 0 {
+		return false
+	}
+	ips, err := opts.resolver().LookupHost(ctx, name)
+	if err != nil {
+		return false
+	}
+	return onlyWildcard(ips, wildcard)
+}
+
+// onlyWildcard reports whether ips is non-empty and holds nothing but
+// addresses of the sorted wildcard set.
+func onlyWildcard(ips, wildcard []string) bool {
+	if len(ips) == 0 || len(wildcard) == 0 {
+		return false
+	}
+	for _, ip := range ips {
+		if _, found := slices.BinarySearch(wildcard, ip); !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// TakeoverFingerprint describes a service whose customer names can be
+// claimed by anyone once the owner deletes them. The JSON form is that of
+// the can-i-take-over-xyz fingerprints.json.
+type TakeoverFingerprint struct {
+	Service string `json:"service"`
+	// CNAMEs are the domains the service hands out to its customers
+	CNAMEs []string `json:"cname"`
+	// Fingerprint is text of the error page served for an unclaimed name
+	Fingerprint string `json:"fingerprint"`
+	// NXDomain is set when an unclaimed name no longer resolves at all
+	NXDomain bool `json:"nxdomain"`
+	// Vulnerable is false for services known to block takeovers
+	Vulnerable *bool `json:"vulnerable,omitempty"`
+}
+
+// takeoverFingerprints are the services checked by -takeover unless
+// -fingerprints loads others.
+var takeoverFingerprints = []TakeoverFingerprint{
+	{Service: "AWS/S3", CNAMEs: []string{"s3.amazonaws.com", "s3-website"}, Fingerprint: "The specified bucket does not exist"},
+	{Service: "AWS/Elastic Beanstalk", CNAMEs: []string{"elasticbeanstalk.com"}, NXDomain: true},
+	{Service: "Microsoft Azure", CNAMEs: []string{"azurewebsites.net", "cloudapp.net", "cloudapp.azure.com", "trafficmanager.net", "blob.core.windows.net", "azure-api.net", "azureedge.net"}, NXDomain: true},
+	{Service: "GitHub Pages", CNAMEs: []string{"github.io"}, Fingerprint: "There isn't a GitHub Pages site here."},
+	{Service: "Heroku", CNAMEs: []string{"herokuapp.com", "herokudns.com"}, Fingerprint: "No such app"},
+	{Service: "Shopify", CNAMEs: []string{"myshopify.com"}, Fingerprint: "Sorry, this shop is currently unavailable."},
+	{Service: "Fastly", CNAMEs: []string{"fastly.net"}, Fingerprint: "Fastly error: unknown domain"},
+	{Service: "Pantheon", CNAMEs: []string{"pantheonsite.io"}, Fingerprint: "The gods are wise, but do not know of the site which you seek."},
+	{Service: "Tumblr", CNAMEs: []string{"domains.tumblr.com"}, Fingerprint: "Whatever you were looking for doesn't currently exist at this address."},
+	{Service: "Bitbucket", CNAMEs: []string{"bitbucket.io"}, Fingerprint: "Repository not found"},
+	{Service: "Surge.sh", CNAMEs: []string{"surge.sh"}, Fingerprint: "project not found"},
+	{Service: "Ghost", CNAMEs: []string{"ghost.io"}, Fingerprint: "The thing you were looking for is no longer here, or never was"},
+	{Service: "Help Scout", CNAMEs: []string{"helpscoutdocs.com"}, Fingerprint: "No settings were found for this company:"},
+	{Service: "Helpjuice", CNAMEs: []string{"helpjuice.com"}, Fingerprint: "We could not find what you're looking for."},
+	{Service: "Readme.io", CNAMEs: []string{"readme.io"}, Fingerprint: "Project doesnt exist... yet!"},
+	{Service: "Strikingly", CNAMEs: []string{"s.strikinglydns.com"}, Fingerprint: "PAGE NOT FOUND."},
+	{Service: "Uberflip", CNAMEs: []string{"read.uberflip.com"}, Fingerprint: "The URL you've accessed does not provide a hub."},
+	{Service: "Unbounce", CNAMEs: []string{"unbouncepages.com"}, Fingerprint: "The requested URL was not found on this server."},
+	{Service: "Webflow", CNAMEs: []string{"proxy.webflow.com", "proxy-ssl.webflow.com"}, Fingerprint: "The page you are looking for doesn't exist or has been moved."},
+	{Service: "WordPress.com", CNAMEs: []string{"wordpress.com"}, Fingerprint: "Do you want to register"},
+	{Service: "Agile CRM", CNAMEs: []string{"agilecrm.com"}, Fingerprint: "Sorry, this page is no longer available."},
+	{Service: "Ngrok", CNAMEs: []string{"ngrok.io"}, Fingerprint: "ngrok.io not found"},
+}
+
+// TakeoverFinding is a host whose CNAME target looks unclaimed.
+type TakeoverFinding struct {
+	Host    string
+	Target  string
+	Service string
+	Reason  string
+}
+
+// loadFingerprints reads a JSON array of fingerprints from path. Entries
+// marked not vulnerable, or without a CNAME or any sign of an unclaimed
+// name, are dropped.
+func loadFingerprints(path string) ([]TakeoverFingerprint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var all []TakeoverFingerprint
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, fmt.Errorf("invalid fingerprint file %s: %w", path, err)
+	}
+	var usable []TakeoverFingerprint
+	for _, fp := range all {
+		if fp.Vulnerable != nil && !*fp.Vulnerable {
+			continue
+		}
+		if fp.Fingerprint == "NXDOMAIN" {
+			fp.Fingerprint, fp.NXDomain = "", true
+		}
+		if len(fp.CNAMEs) > 0 && (fp.Fingerprint != "" || fp.NXDomain) {
+			usable = append(usable, fp)
+		}
+	}
+	if len(usable) == 0 {
+		return nil, fmt.Errorf("%s holds no usable fingerprints", path)
+	}
+	return usable, nil
+}
+
+// terminalCNAME returns the last CNAME target in the answer for host and
+// whether that target does not exist. It returns "" when host is no alias.
+func terminalCNAME(host string) (string, bool, error) {
+	resp, err := rawQuery(host, dnsmessage.TypeA, "")
+	if err != nil {
+		return "", false, err
+	}
+	var target string
+	for _, rr := range resp.Answers {
+		if cname, ok := rr.Body.(*dnsmessage.CNAMEResource); ok {
+			target = strings.ToLower(strings.TrimSuffix(cname.CNAME.String(), "."))
+		}
+	}
+	return target, resp.Header.RCode == dnsmessage.RCodeNameError, nil
+}
+
+// matchesCNAME reports whether target contains one of the CNAMEs of fp.
+// They are matched as substrings, the way fingerprints.json lists them,
+// so "s3-website" covers every regional website endpoint.
+func (fp TakeoverFingerprint) matchesCNAME(target string) bool {
+	for _, cname := range fp.CNAMEs {
+		if cname = strings.ToLower(strings.Trim(cname, ".")); cname != "" && strings.Contains(target, cname) {
+			return true
+		}
+	}
+	return false
+}
+
+// unclaimedBody fetches host over HTTPS and then HTTP and reports whether
+// either page contains fingerprint.
+func unclaimedBody(host, fingerprint string) bool {
+	for _, scheme := range []string{"https", "http"} {
+		resp, err := probeClient.Get(scheme + "://" + host + "/")
+		if err != nil {
+			continue
+		}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+		resp.Body.Close()
+		if strings.Contains(string(body), fingerprint) {
+			return true
+		}
+	}
+	return false
+}
+
+// detectTakeover checks the terminal CNAME of host against fingerprints.
+// A target that does not exist is always reported, naming the service
+// when one matches.
+func detectTakeover(host string, fingerprints []TakeoverFingerprint) (TakeoverFinding, bool) {
+	target, nxdomain, err := terminalCNAME(host)
+	if err != nil || target == "" {
+		return TakeoverFinding{}, false
+	}
+	finding := TakeoverFinding{Host: host, Target: target}
+	for _, fp := range fingerprints {
+		if !fp.matchesCNAME(target) {
+			continue
+		}
+		finding.Service = fp.Service
+		switch {
+		case nxdomain:
+			finding.Reason = "CNAME target does not exist"
+			return finding, true
+		case fp.Fingerprint != "" && unclaimedBody(host, fp.Fingerprint):
+			finding.Reason = fmt.Sprintf("unclaimed page (%q)", fp.Fingerprint)
+			return finding, true
+		}
+		return finding, false
+	}
+	if nxdomain {
+		finding.Reason = "CNAME target does not exist"
+		return finding, true
+	}
+	return finding, false
+}
+
+// checkTakeovers prints every host whose CNAME points at an unclaimed
+// name. Each is a lead to confirm by claiming the name, not proof.
+func checkTakeovers(hosts []string, fingerprints []TakeoverFingerprint) []TakeoverFinding {
+	var findings []TakeoverFinding
+	for _, host := range hosts {
+		finding, ok := detectTakeover(host, fingerprints)
+		if !ok {
+			continue
+		}
+		findings = append(findings, finding)
+		service := finding.Service
+		if service == "" {
+			service = "unknown service"
+		}
+		fmt.Fprintf(status, " - [TAKEOVER: %s] %s -> %s, %s\n", service, host, finding.Target, finding.Reason)
+	}
+	return findings
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// terraformState is the part of a Terraform state file (format version 4)
+// that records the managed resources.
+type terraformState struct {
+	Resources []struct {
+		Module    string `json:"module"`
+		Type      string `json:"type"`
+		Name      string `json:"name"`
+		Instances []struct {
+			Attributes map[string]any `json:"attributes"`
+		} `json:"instances"`
+	} `json:"resources"`
+}
+
+// parseTerraformState returns the subdomains of domain found in the string
+// attributes of every resource instance in a Terraform state, nested
+// values included. Record names, aliases, certificate SANs and endpoint
+// URLs all end up there.
+func parseTerraformState(r io.Reader, domain string) ([]string, error) {
+	var state terraformState
+	if err := json.NewDecoder(r).Decode(&state); err != nil {
+		return nil, fmt.Errorf("invalid Terraform state: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	var result []string
+	for _, resource := range state.Resources {
+		address := resource.Type + "." + resource.Name
+		if resource.Module != "" {
+			address = resource.Module + "." + address
+		}
+		for _, instance := range resource.Instances {
+			for _, value := range terraformStrings(instance.Attributes) {
+				for _, name := range fqdnPattern.FindAllString(value, -1) {
+					name = strings.ToLower(name)
+					if !seen[name] && strings.HasSuffix(name, "."+domain) {
+						seen[name] = true
+						result = append(result, name)
+						fmt.Fprintf(status, " - [TF-STATE] %s (%s)\n", name, address)
+					}
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+// terraformStrings returns every string held by an attribute value decoded
+// from JSON, walking objects in key order and lists in element order.
+func terraformStrings(value any) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		var all []string
+		for _, item := range v {
+			all = append(all, terraformStrings(item)...)
+		}
+		return all
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		var all []string
+		for _, key := range keys {
+			all = append(all, terraformStrings(v[key])...)
+		}
+		return all
+	}
+	return nil
+}
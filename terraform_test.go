@@ -0,0 +1,63 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTerraformState(t *testing.T) {
+	const state = `{
+  "version": 4,
+  "resources": [
+    {
+      "type": "aws_route53_record",
+      "name": "api",
+      "instances": [
+        {"attributes": {"name": "API.example.com", "records": ["lb-1.example.com", "other.example.net"], "ttl": 300}}
+      ]
+    },
+    {
+      "module": "module.cdn",
+      "type": "aws_cloudfront_distribution",
+      "name": "site",
+      "instances": [
+        {"attributes": {"aliases": ["static.example.com", "api.example.com"], "origin": [{"domain_name": "origin.internal.example.com"}]}},
+        {"attributes": {"comment": "see https://status.example.com/health for details"}}
+      ]
+    },
+    {"type": "random_id", "name": "suffix", "instances": [{"attributes": {"hex": "example.com"}}]}
+  ]
+}`
+	tests := []struct {
+		name    string
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		{"state", state, []string{"api.example.com", "lb-1.example.com", "static.example.com", "origin.internal.example.com", "status.example.com"}, false},
+		{"no resources", `{"version": 4}`, nil, false},
+		{"not json", `terraform {`, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseTerraformState(strings.NewReader(tt.input), "example.com")
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: parseTerraformState error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: parseTerraformState = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTerraformStrings(t *testing.T) {
+	value := map[string]any{
+		"b": []any{"two", 3.0, map[string]any{"z": "four", "y": true}},
+		"a": "one",
+		"c": nil,
+	}
+	want := []string{"one", "two", "four"}
+	if got := terraformStrings(value); !reflect.DeepEqual(got, want) {
+		t.Errorf("terraformStrings = %q, want %q", got, want)
+	}
+}
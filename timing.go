@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ScanTimer records how long each domain, and each enumeration or
+// analysis method run for it, took during one scan.
+type ScanTimer struct {
+	StartTime time.Time
+	EndTime   time.Time
+
+	mu      sync.Mutex
+	domains map[string]*DomainTiming
+}
+
+// DomainTiming is the run time of one domain and of its methods in the
+// order they ran.
+type DomainTiming struct {
+	StartTime time.Time
+	EndTime   time.Time
+	Methods   []MethodTiming
+}
+
+// MethodTiming is one method run for a domain.
+type MethodTiming struct {
+	Method    string
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+// domainDuration is a domain's timing as written in -json mode.
+type domainDuration struct {
+	Domain     string           `json:"domain"`
+	DurationMS int64            `json:"duration_ms"`
+	Methods    map[string]int64 `json:"methods_ms"`
+}
+
+func newScanTimer() *ScanTimer {
+	return &ScanTimer{StartTime: time.Now(), domains: make(map[string]*DomainTiming)}
+}
+
+// startDomain marks the start of domain and returns the func that marks
+// its end.
+func (t *ScanTimer) startDomain(domain string) func() {
+	t.mu.Lock()
+	timing := &DomainTiming{StartTime: time.Now()}
+	t.domains[domain] = timing
+	t.mu.Unlock()
+	return func() {
+		t.mu.Lock()
+		timing.EndTime = time.Now()
+		t.mu.Unlock()
+	}
+}
+
+// start marks the start of method for domain and returns the func that
+// marks its end.
+func (t *ScanTimer) start(domain, method string) func() {
+	begin := time.Now()
+	return func() { t.record(domain, method, begin, time.Now()) }
+}
+
+// record adds a run of method for domain that took from begin to end.
+func (t *ScanTimer) record(domain, method string, begin, end time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if timing, ok := t.domains[domain]; ok {
+		timing.Methods = append(timing.Methods, MethodTiming{Method: method, StartTime: begin, EndTime: end})
+	}
+}
+
+// report marks the end of the scan and prints the total wall-clock time,
+// the time per domain, slowest first, and each domain's methods, slowest
+// first. In -json mode every domain is also written as a JSON line.
+func (t *ScanTimer) report(output *os.File) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.EndTime = time.Now()
+
+	domains := make([]string, 0, len(t.domains))
+	for domain := range t.domains {
+		domains = append(domains, domain)
+	}
+	duration := func(start, end time.Time) time.Duration {
+		if end.IsZero() {
+			end = t.EndTime
+		}
+		return end.Sub(start)
+	}
+	sort.Slice(domains, func(i, j int) bool {
+		a, b := t.domains[domains[i]], t.domains[domains[j]]
+		return duration(a.StartTime, a.EndTime) > duration(b.StartTime, b.EndTime)
+	})
+
+	fmt.Fprintf(status, "\nScan timing: %s total\n", t.EndTime.Sub(t.StartTime).Round(time.Millisecond))
+	for _, domain := range domains {
+		timing := t.domains[domain]
+		took := duration(timing.StartTime, timing.EndTime)
+		fmt.Fprintf(status, " - %s %s\n", domain, took.Round(time.Millisecond))
+
+		methods := append([]MethodTiming(nil), timing.Methods...)
+		sort.SliceStable(methods, func(i, j int) bool {
+			return methods[i].EndTime.Sub(methods[i].StartTime) > methods[j].EndTime.Sub(methods[j].StartTime)
+		})
+		summary := domainDuration{Domain: domain, DurationMS: took.Milliseconds(), Methods: make(map[string]int64)}
+		for _, m := range methods {
+			fmt.Fprintf(status, "     %-22s %s\n", m.Method, m.EndTime.Sub(m.StartTime).Round(time.Millisecond))
+			summary.Methods[m.Method] += m.EndTime.Sub(m.StartTime).Milliseconds()
+		}
+		if jsonOutput {
+			writeJSONLine(summary, domain, output)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// commonTLDs are tried after the TLDs seen in CT logs, in this order.
+var commonTLDs = []string{"com", "net", "org", "io", "co", "info", "biz", "us", "co.uk", "de", "app", "dev"}
+
+// siblingDomain swaps the public suffix of domain for tld, keeping any
+// labels in front of the registrable name: dev.example.com with "io"
+// gives dev.example.io.
+func siblingDomain(domain, tld string) string {
+	suffix, _ := publicsuffix.PublicSuffix(domain)
+	return strings.TrimSuffix(domain, suffix) + tld
+}
+
+// ctTLDs returns the public suffixes the registrable name of domain is
+// used under on certificates CertSpotter has logged for it, most frequent
+// first. The suffix of domain itself is left out.
+func ctTLDs(domain string) ([]string, error) {
+	apex, err := publicsuffix.EffectiveTLDPlusOne(domain)
+	if err != nil {
+		return nil, err
+	}
+	own, _ := publicsuffix.PublicSuffix(apex)
+	label := strings.TrimSuffix(apex, "."+own)
+
+	names, err := certSpotterNames(domain)
+	counts := make(map[string]int)
+	for _, name := range names {
+		other, perr := publicsuffix.EffectiveTLDPlusOne(name)
+		if perr != nil {
+			continue
+		}
+		suffix, _ := publicsuffix.PublicSuffix(other)
+		if suffix != own && other == label+"."+suffix {
+			counts[suffix]++
+		}
+	}
+	tlds := make([]string, 0, len(counts))
+	for tld := range counts {
+		tlds = append(tlds, tld)
+	}
+	sort.Slice(tlds, func(i, j int) bool {
+		if counts[tlds[i]] != counts[tlds[j]] {
+			return counts[tlds[i]] > counts[tlds[j]]
+		}
+		return tlds[i] < tlds[j]
+	})
+	return tlds, err
+}
+
+// expandTLDs returns domains followed by up to maxTLDs registered siblings
+// of each under other TLDs, and a map from every sibling to its TLD. TLDs
+// seen in CT logs are tried before commonTLDs, a sibling only counts once
+// it has NS records and is not a target already.
+func expandTLDs(ctx context.Context, domains []string, maxTLDs int) ([]string, map[string]string) {
+	expanded := make(map[string]string)
+	targets := make(map[string]bool, len(domains))
+	for _, domain := range domains {
+		targets[normalizeDomain(domain)] = true
+	}
+	result := append([]string(nil), domains...)
+	for _, domain := range domains {
+		domain = normalizeDomain(domain)
+		fmt.Fprintf(status, "\nLooking for sibling TLDs of %s...\n", domain)
+		seenInCT, err := ctTLDs(domain)
+		if err != nil {
+			log.Printf("CT lookup for the TLDs of %s incomplete: %v\n", domain, err)
+		}
+		own, _ := publicsuffix.PublicSuffix(domain)
+		tried := map[string]bool{own: true}
+		var added int
+		for i, tld := range append(seenInCT, commonTLDs...) {
+			if added == maxTLDs || ctx.Err() != nil {
+				break
+			}
+			if tried[tld] {
+				continue
+			}
+			tried[tld] = true
+			sibling := siblingDomain(domain, tld)
+			if _, ok := expanded[sibling]; ok || targets[sibling] {
+				continue
+			}
+			if ns, err := resolver.LookupNS(ctx, sibling); err != nil || len(ns) == 0 {
+				continue
+			}
+			origin := "registered"
+			if i < len(seenInCT) {
+				origin = "seen in CT logs"
+			}
+			fmt.Fprintf(status, " - [TLD .%s] %s (%s)\n", tld, sibling, origin)
+			expanded[sibling] = tld
+			result = append(result, sibling)
+			added++
+		}
+	}
+	return result, expanded
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+)
+
+// urlhausKey is the abuse.ch Auth-Key sent with URLhaus lookups. The API
+// refuses anonymous queries.
+var urlhausKey string
+
+var errURLhausAuth = errors.New("URLhaus rejected the request (401), check -urlhaus-key")
+
+// URLhausResult is a host listed in URLhaus with the threats and tags of
+// its listed URLs, each named once.
+type URLhausResult struct {
+	Host    string   `json:"host"`
+	Threats []string `json:"threats"`
+	Tags    []string `json:"tags"`
+}
+
+// checkURLhaus looks subdomain up in the URLhaus host database. It reports
+// whether the host has distributed malware, and if so what its URLs were
+// listed for.
+func checkURLhaus(subdomain string) (URLhausResult, bool, error) {
+	form := url.Values{"host": {subdomain}}
+	req, err := http.NewRequest(http.MethodPost, "https://urlhaus-api.abuse.ch/v1/host/", strings.NewReader(form.Encode()))
+	if err != nil {
+		return URLhausResult{}, false, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if urlhausKey != "" {
+		req.Header.Set("Auth-Key", urlhausKey)
+	}
+
+	resp, err := apiClient.Do(req)
+	if err != nil {
+		return URLhausResult{}, false, fmt.Errorf("URLhaus request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized:
+		return URLhausResult{}, false, errURLhausAuth
+	default:
+		return URLhausResult{}, false, fmt.Errorf("URLhaus returned %s", resp.Status)
+	}
+
+	var result struct {
+		QueryStatus string `json:"query_status"`
+		URLs        []struct {
+			Threat string   `json:"threat"`
+			Tags   []string `json:"tags"`
+		} `json:"urls"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return URLhausResult{}, false, fmt.Errorf("failed to decode URLhaus response: %w", err)
+	}
+	switch result.QueryStatus {
+	case "ok":
+	case "no_results", "invalid_host":
+		return URLhausResult{}, false, nil
+	default:
+		return URLhausResult{}, false, fmt.Errorf("URLhaus query failed: %s", result.QueryStatus)
+	}
+
+	listed := URLhausResult{Host: subdomain, Threats: []string{}, Tags: []string{}}
+	for _, u := range result.URLs {
+		if u.Threat != "" && !slices.Contains(listed.Threats, u.Threat) {
+			listed.Threats = append(listed.Threats, u.Threat)
+		}
+		for _, tag := range u.Tags {
+			if !slices.Contains(listed.Tags, tag) {
+				listed.Tags = append(listed.Tags, tag)
+			}
+		}
+	}
+	return listed, true, nil
+}
+
+// checkAllURLhaus looks every host up in URLhaus and returns the listed
+// ones keyed by host. In -json mode each listed host is also written as a
+// JSON line.
+func checkAllURLhaus(hosts []string, output *os.File) map[string]URLhausResult {
+	listed := make(map[string]URLhausResult)
+	for _, host := range hosts {
+		result, ok, err := checkURLhaus(host)
+		if err != nil {
+			log.Printf("URLhaus lookup for %s failed: %v\n", host, err)
+			if errors.Is(err, errURLhausAuth) {
+				break
+			}
+			continue
+		}
+		if !ok {
+			continue
+		}
+		listed[host] = result
+		details := slices.Concat(result.Threats, result.Tags)
+		if len(details) > 0 {
+			fmt.Fprintf(status, " - [MALWARE: URLhaus] %s (%s)\n", host, strings.Join(details, ","))
+		} else {
+			fmt.Fprintf(status, " - [MALWARE: URLhaus] %s\n", host)
+		}
+		if jsonOutput {
+			writeJSONLine(result, host, output)
+		}
+	}
+	return listed
+}
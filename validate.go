@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// outputSchemaJSON describes every line written in -json mode.
+//
+//go:embed schema.json
+var outputSchemaJSON []byte
+
+// validateOutput checks every -json line against outputSchemaJSON before it
+// is written, it is set by -validate-output.
+var validateOutput bool
+
+// invalidLines counts the -json lines left out because they did not match
+// the schema.
+var invalidLines atomic.Int64
+
+// outputSchema compiles outputSchemaJSON on first use.
+var outputSchema = sync.OnceValues(func() (*jsonschema.Schema, error) {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(outputSchemaJSON))
+	if err != nil {
+		return nil, fmt.Errorf("invalid embedded schema: %w", err)
+	}
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource("schema.json", doc); err != nil {
+		return nil, err
+	}
+	return compiler.Compile("schema.json")
+})
+
+// validateJSONOutput reports whether data, one -json line, matches the
+// output schema.
+func validateJSONOutput(data []byte) error {
+	schema, err := outputSchema()
+	if err != nil {
+		return err
+	}
+	v, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	return schema.Validate(v)
+}
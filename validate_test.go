@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestValidateJSONOutput(t *testing.T) {
+	now := time.Now()
+	lines := []struct {
+		name string
+		v    any
+	}{
+		{"result", Result{Domain: "example.com", Subdomain: "api.example.com", Source: "axfr", RecordType: "A", Timestamp: now, IPs: []string{"192.0.2.10"}, CloudProvider: "AWS/CloudFront", CDNProvider: "Cloudflare"}},
+		{"expanded tld", Result{Domain: "example.de", Subdomain: "shop.example.de", Source: "sni", Timestamp: now, TLD: "de"}},
+		{"bare result", Result{Subdomain: "api.example.com", Source: "axfr", Timestamp: now}},
+		{"probe", ProbeResult{Host: "api.example.com", URL: "https://api.example.com", StatusCode: 200, FinalURL: "https://api.example.com/", Server: "nginx", Timestamp: now}},
+		{"security headers", SecurityHeaderReport{Host: "api.example.com", HSTS: true, Missing: []string{"Content-Security-Policy"}, Score: 20, Downgrade: "HTTPS-ONLY", HPKP: &HPKPConfig{MaxAge: 5184000}}},
+		{"bare security headers", SecurityHeaderReport{Host: "api.example.com"}},
+		{"timing", domainDuration{Domain: "example.com", DurationMS: 1200, Methods: map[string]int64{"axfr": 300, "sni": 900}}},
+		{"whois", WhoisRecord{Domain: "example.com", Server: "whois.verisign-grs.com", Registrar: "Example Registrar", NameServers: []string{"a.iana-servers.net"}, Timestamp: now}},
+		{"registrant group", RegistrantGroup{Registrant: "example inc", Domains: []string{"example.com", "example.net"}}},
+		{"rdap", rdapRecord{Host: "api.example.com", IP: "192.0.2.10", RDAPResult: RDAPResult{Name: "EXAMPLE-NET", Handle: "NET-192-0-2-0-1", CIDRs: []string{"192.0.2.0/24"}}}},
+		{"rdap without cidrs", rdapRecord{Host: "api.example.com", IP: "192.0.2.10"}},
+		{"waf", WAFResult{Host: "api.example.com", WAF: "Cloudflare", Confidence: 0.9}},
+		{"no waf", WAFResult{Host: "api.example.com"}},
+		{"dnskey", DNSKEYRecord{Domain: "example.com", Flags: 257, Protocol: 3, Algorithm: 8, KeyTag: 20326, KSK: true, KeyBits: 2048, PublicKey: "AwEAAa=="}},
+		{"urlhaus", URLhausResult{Host: "api.example.com", Threats: []string{"malware_download"}, Tags: []string{}}},
+	}
+	for _, tt := range lines {
+		line, err := json.Marshal(tt.v)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if err := validateJSONOutput(line); err != nil {
+			t.Errorf("%s line %s does not match the schema: %v", tt.name, line, err)
+		}
+	}
+
+	for _, line := range []string{
+		`{"subdomain":"api.example.com","source":"sni"}`,
+		`{"subdomain":"api.example.com","source":"sni","timestamp":"2024-01-01T00:00:00Z","ips":"192.0.2.10"}`,
+		`{"host":"api.example.com","waf":"Cloudflare","unknown":true}`,
+		`{"host":"api.example.com","score":20}`,
+		`{"domain":"example.com","flags":257,"protocol":3,"algorithm":8,"key_tag":70000,"ksk":true,"public_key":"AwEAAa=="}`,
+		`{"domain":"example.com","flags":257,"protocol":3,"algorithm":8,"key_tag":1,"ksk":true,"public_key":"AwEAAa==","unknown":true}`,
+		`{"host":"api.example.com","threats":null,"tags":[]}`,
+		`[]`,
+		`{`,
+	} {
+		if err := validateJSONOutput([]byte(line)); err == nil {
+			t.Errorf("validateJSONOutput(%s) = nil, want an error", line)
+		}
+	}
+}
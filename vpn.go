@@ -0,0 +1,91 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sslVPNPorts are the non-standard HTTPS ports SSL VPN portals listen on.
+var sslVPNPorts = []int{4433, 8443, 10443, 4000}
+
+// sslVPNBanners maps substrings of a portal's response, matched
+// case-insensitively, to the product serving it. The first match wins.
+var sslVPNBanners = []struct {
+	product string
+	marker  string
+}{
+	{"GlobalProtect", "global-protect"},
+	{"GlobalProtect", "globalprotect"},
+	{"AnyConnect", "webvpn"},
+	{"AnyConnect", "/+cscoe+/"},
+	{"Pulse Secure", "/dana-na/"},
+	{"Pulse Secure", "pulse secure"},
+	{"FortiGate", "/remote/login"},
+	{"FortiGate", "fgt_lang"},
+	{"Citrix Gateway", "/vpn/index.html"},
+	{"Citrix Gateway", "nsc_"},
+	{"SonicWall", "sonicwall"},
+	{"OpenVPN Access Server", "openvpn"},
+	{"Check Point", "/sslvpn/login/"},
+}
+
+// VPNHit is an SSL VPN portal found on one port of a host.
+type VPNHit struct {
+	Port    int
+	Product string
+}
+
+// sslVPNProbe requests / over TLS on each port, reads the first 4KB of the
+// response and matches it against the known VPN portal banners.
+func sslVPNProbe(host string, ports []int, timeout time.Duration) []VPNHit {
+	var hits []VPNHit
+	for _, port := range ports {
+		banner, err := grabTLSBanner(host, port, timeout)
+		if err != nil {
+			continue
+		}
+		banner = strings.ToLower(banner)
+		for _, b := range sslVPNBanners {
+			if strings.Contains(banner, b.marker) {
+				hits = append(hits, VPNHit{Port: port, Product: b.product})
+				break
+			}
+		}
+	}
+	return hits
+}
+
+func grabTLSBanner(host string, port int, timeout time.Duration) (string, error) {
+	dialer := newDialer()
+	dialer.Timeout = timeout
+	conn, err := tls.DialWithDialer(dialer, "tcp", net.JoinHostPort(host, strconv.Itoa(port)), &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	fmt.Fprintf(conn, "GET / HTTP/1.1\r\nHost: %s\r\nUser-Agent: Mozilla/5.0\r\nConnection: close\r\n\r\n", host)
+	buf, err := io.ReadAll(io.LimitReader(conn, 4096))
+	if len(buf) == 0 {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// checkSSLVPN probes every host on the SSL VPN ports.
+func checkSSLVPN(hosts []string) {
+	for _, host := range hosts {
+		for _, hit := range sslVPNProbe(host, sslVPNPorts, dnsQueryTimeout) {
+			fmt.Fprintf(status, " - [SSL-VPN: %s] %s\n", hit.Product, net.JoinHostPort(host, strconv.Itoa(hit.Port)))
+		}
+	}
+}
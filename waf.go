@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// wafProbeAgent looks like a browser with a classic SQL injection and XSS
+// string appended, enough for most WAFs to block or challenge the request.
+const wafProbeAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) ' OR 1=1-- <script>alert(1)</script>"
+
+// wafSignature is one piece of evidence for a WAF. Header and cookie names
+// match case-insensitively, a non-empty value must appear in the header.
+type wafSignature struct {
+	header string
+	value  string
+	cookie string
+	body   string
+	weight float64
+}
+
+var wafSignatures = map[string][]wafSignature{
+	"Cloudflare": {
+		{header: "Cf-Ray", weight: 0.6},
+		{header: "Server", value: "cloudflare", weight: 0.4},
+		{cookie: "__cf_bm", weight: 0.3},
+		{body: "Attention Required! | Cloudflare", weight: 0.5},
+	},
+	"Akamai": {
+		{header: "Server", value: "AkamaiGHost", weight: 0.6},
+		{header: "X-Akamai-Transformed", weight: 0.3},
+		{cookie: "ak_bmsc", weight: 0.3},
+		{body: "Access Denied", weight: 0.2},
+	},
+	"Imperva": {
+		{header: "X-Iinfo", weight: 0.6},
+		{header: "X-CDN", value: "Incapsula", weight: 0.6},
+		{cookie: "incap_ses_", weight: 0.4},
+		{cookie: "visid_incap_", weight: 0.4},
+		{body: "Incapsula incident ID", weight: 0.6},
+	},
+	"AWS WAF": {
+		{header: "X-Amzn-Waf-Action", weight: 0.8},
+		{cookie: "aws-waf-token", weight: 0.6},
+		{header: "Server", value: "awselb", weight: 0.2},
+		{body: "Request blocked", weight: 0.3},
+	},
+	"Sucuri": {
+		{header: "X-Sucuri-Id", weight: 0.7},
+		{header: "Server", value: "Sucuri", weight: 0.5},
+		{body: "Sucuri WebSite Firewall", weight: 0.6},
+	},
+	"F5 BIG-IP ASM": {
+		{cookie: "TS01", weight: 0.4},
+		{header: "Server", value: "BigIP", weight: 0.3},
+		{body: "The requested URL was rejected", weight: 0.6},
+	},
+	"ModSecurity": {
+		{header: "Server", value: "mod_security", weight: 0.7},
+		{body: "Mod_Security", weight: 0.5},
+		{body: "This error was generated by Mod_Security", weight: 0.7},
+	},
+	"Barracuda": {
+		{cookie: "barra_counter_session", weight: 0.7},
+		{body: "Barracuda Networks", weight: 0.4},
+	},
+	"FortiWeb": {
+		{cookie: "FORTIWAFSID", weight: 0.7},
+		{body: ".fgd_icon", weight: 0.4},
+	},
+}
+
+// detectWAF sends a request carrying an attack string in its User-Agent to
+// host and matches the response against known WAF fingerprints. It returns
+// the best matching WAF and a confidence between 0 and 1, or "" when
+// nothing matched.
+func detectWAF(host string) (string, float64, error) {
+	req, err := http.NewRequest(http.MethodGet, "https://"+host+"/", nil)
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("User-Agent", wafProbeAgent)
+	resp, err := probeClient.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+
+	var best string
+	var confidence float64
+	for name, signatures := range wafSignatures {
+		var score float64
+		for _, sig := range signatures {
+			if sig.matches(resp, string(body)) {
+				score += sig.weight
+			}
+		}
+		if score == 0 {
+			continue
+		}
+		// A block page on the payload is stronger evidence than headers alone
+		switch resp.StatusCode {
+		case http.StatusForbidden, http.StatusNotAcceptable, http.StatusTooManyRequests, http.StatusNotImplemented:
+			score += 0.2
+		}
+		score = min(score, 1)
+		if score > confidence || (score == confidence && name < best) {
+			best, confidence = name, score
+		}
+	}
+	return best, confidence, nil
+}
+
+func (sig wafSignature) matches(resp *http.Response, body string) bool {
+	switch {
+	case sig.header != "":
+		values := resp.Header.Values(sig.header)
+		if sig.value == "" {
+			return len(values) > 0
+		}
+		for _, v := range values {
+			if strings.Contains(strings.ToLower(v), strings.ToLower(sig.value)) {
+				return true
+			}
+		}
+	case sig.cookie != "":
+		for _, cookie := range resp.Cookies() {
+			if strings.HasPrefix(strings.ToLower(cookie.Name), strings.ToLower(sig.cookie)) {
+				return true
+			}
+		}
+	case sig.body != "":
+		return strings.Contains(body, sig.body)
+	}
+	return false
+}
+
+// WAFResult is the WAF detection of one host as written in -json mode. WAF
+// is empty when the host answered without matching any fingerprint.
+type WAFResult struct {
+	Host       string  `json:"host"`
+	WAF        string  `json:"waf"`
+	Confidence float64 `json:"confidence,omitempty"`
+}
+
+// checkWAF prints the WAF in front of every host that has one and returns
+// the names keyed by host. In -json mode every host that answered is also
+// written as a WAFResult line.
+func checkWAF(hosts []string, output *os.File) map[string]string {
+	wafs := make(map[string]string)
+	for _, host := range hosts {
+		name, confidence, err := detectWAF(host)
+		if err != nil {
+			log.Printf("Failed to probe %s for a WAF: %v\n", host, err)
+			continue
+		}
+		if name != "" {
+			wafs[host] = name
+			fmt.Fprintf(status, " - [WAF: %s] %s (confidence %.0f%%)\n", name, host, confidence*100)
+		}
+		if jsonOutput {
+			writeJSONLine(WAFResult{Host: host, WAF: name, Confidence: confidence}, host, output)
+		}
+	}
+	return wafs
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var wellKnownPaths = []string{
+	"/.well-known/api-catalog",
+	"/.well-known/host-meta",
+	"/.well-known/security.txt",
+}
+
+// securityTxtURLFields are the RFC 9116 fields whose values are URIs.
+var securityTxtURLFields = map[string]bool{
+	"contact":         true,
+	"canonical":       true,
+	"hiring":          true,
+	"policy":          true,
+	"acknowledgments": true,
+	"encryption":      true,
+}
+
+var urlPattern = regexp.MustCompile(`https?://[^\s"'<>\\)]+`)
+
+// probeWellKnown fetches the well-known discovery documents of host and
+// returns every URL referenced in them.
+func probeWellKnown(host string) []string {
+	seen := make(map[string]bool)
+	var result []string
+	add := func(u string) {
+		u = strings.TrimRight(u, ".,;")
+		if !seen[u] {
+			seen[u] = true
+			result = append(result, u)
+		}
+	}
+
+	for _, path := range wellKnownPaths {
+		body, ok := fetchText("https://" + host + path)
+		if !ok {
+			continue
+		}
+		if strings.HasSuffix(path, "security.txt") {
+			for _, u := range parseSecurityTxt(body) {
+				add(u)
+			}
+			continue
+		}
+		// api-catalog is a JSON linkset and host-meta an XRD document, both
+		// carry their links as plain absolute URLs
+		for _, u := range urlPattern.FindAllString(body, -1) {
+			add(u)
+		}
+	}
+	return result
+}
+
+// fetchText returns the body of u if it is served with status 200.
+func fetchText(u string) (string, bool) {
+	resp, err := probeClient.Get(u)
+	if err != nil {
+		return "", false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", false
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", false
+	}
+	return string(body), true
+}
+
+// parseSecurityTxt extracts the URI values of a security.txt file.
+func parseSecurityTxt(body string) []string {
+	var result []string
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		name, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok || !securityTxtURLFields[strings.ToLower(strings.TrimSpace(name))] {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if strings.HasPrefix(value, "http://") || strings.HasPrefix(value, "https://") {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// wellKnownEnumerate probes every host and returns the new subdomains of
+// domain referenced by their well-known documents.
+func wellKnownEnumerate(domain string, hosts []string) []string {
+	known := make(map[string]bool, len(hosts))
+	for _, host := range hosts {
+		known[host] = true
+	}
+
+	var result []string
+	for _, host := range hosts {
+		for _, ref := range probeWellKnown(host) {
+			fmt.Fprintf(status, " - %s references %s\n", host, ref)
+			u, err := url.Parse(ref)
+			if err != nil {
+				continue
+			}
+			name := strings.ToLower(u.Hostname())
+			if !known[name] && strings.HasSuffix(name, "."+domain) {
+				known[name] = true
+				result = append(result, name)
+			}
+		}
+	}
+	return result
+}
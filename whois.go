@@ -0,0 +1,350 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// whoisIANA answers which WHOIS server is authoritative for each TLD.
+const whoisIANA = "whois.iana.org"
+
+// whoisWorkers is how many WHOIS lookups run at once across all TLDs.
+const whoisWorkers = 8
+
+// whoisInterval is the minimum gap between two queries to the registry of
+// one TLD. Registries that throttle harder get a longer gap.
+var whoisInterval = map[string]time.Duration{
+	"":   2 * time.Second,
+	"de": 5 * time.Second,
+	"eu": 5 * time.Second,
+	"uk": 3 * time.Second,
+	"jp": 3 * time.Second,
+}
+
+// WhoisRecord holds the registration details of one domain.
+type WhoisRecord struct {
+	Domain          string    `json:"domain"`
+	Server          string    `json:"server"`
+	Registrar       string    `json:"registrar,omitempty"`
+	RegistrantOrg   string    `json:"registrant_org,omitempty"`
+	RegistrantEmail string    `json:"registrant_email,omitempty"`
+	Created         string    `json:"created,omitempty"`
+	Expires         string    `json:"expires,omitempty"`
+	NameServers     []string  `json:"name_servers,omitempty"`
+	SameRegistrant  []string  `json:"same_registrant,omitempty"`
+	Timestamp       time.Time `json:"timestamp"`
+}
+
+// RegistrantGroup is a set of domains that likely share a registrant, as
+// written in -json mode.
+type RegistrantGroup struct {
+	Registrant string   `json:"registrant"`
+	Domains    []string `json:"domains"`
+}
+
+// whoisFields maps the keys registries use to the record field they fill.
+var whoisFields = map[string]func(*WhoisRecord) *string{
+	"registrar":                              func(r *WhoisRecord) *string { return &r.Registrar },
+	"registrant organization":                func(r *WhoisRecord) *string { return &r.RegistrantOrg },
+	"registrant organisation":                func(r *WhoisRecord) *string { return &r.RegistrantOrg },
+	"org":                                    func(r *WhoisRecord) *string { return &r.RegistrantOrg },
+	"registrant email":                       func(r *WhoisRecord) *string { return &r.RegistrantEmail },
+	"registrant e-mail":                      func(r *WhoisRecord) *string { return &r.RegistrantEmail },
+	"creation date":                          func(r *WhoisRecord) *string { return &r.Created },
+	"created":                                func(r *WhoisRecord) *string { return &r.Created },
+	"registered on":                          func(r *WhoisRecord) *string { return &r.Created },
+	"registry expiry date":                   func(r *WhoisRecord) *string { return &r.Expires },
+	"registrar registration expiration date": func(r *WhoisRecord) *string { return &r.Expires },
+	"expiry date":                            func(r *WhoisRecord) *string { return &r.Expires },
+	"paid-till":                              func(r *WhoisRecord) *string { return &r.Expires },
+}
+
+// whoisLimiter spaces out queries per TLD.
+type whoisLimiter struct {
+	mu   sync.Mutex
+	next map[string]time.Time
+}
+
+// wait blocks until a query for tld may be sent and reserves the slot.
+func (l *whoisLimiter) wait(tld string) {
+	interval, ok := whoisInterval[tld]
+	if !ok {
+		interval = whoisInterval[""]
+	}
+	l.mu.Lock()
+	at := time.Now()
+	if next := l.next[tld]; next.After(at) {
+		at = next
+	}
+	l.next[tld] = at.Add(interval)
+	l.mu.Unlock()
+	time.Sleep(time.Until(at))
+}
+
+// apexDomains returns the registrable domain of every name, once each.
+func apexDomains(names []string) []string {
+	seen := make(map[string]bool)
+	var apexes []string
+	for _, name := range names {
+		apex, err := publicsuffix.EffectiveTLDPlusOne(strings.ToLower(strings.TrimSuffix(name, ".")))
+		if err != nil || seen[apex] {
+			continue
+		}
+		seen[apex] = true
+		apexes = append(apexes, apex)
+	}
+	return apexes
+}
+
+// parallelWHOIS looks up every domain with a pool of workers, keeping
+// queries to the same TLD registry apart by whoisInterval. Lookups that
+// fail are logged and left out.
+func parallelWHOIS(domains []string) map[string]WhoisRecord {
+	limiter := &whoisLimiter{next: make(map[string]time.Time)}
+	var servers sync.Map
+	jobs := make(chan string)
+	results := make(map[string]WhoisRecord)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < whoisWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for domain := range jobs {
+				record, err := whoisLookup(domain, limiter, &servers)
+				if err != nil {
+					log.Printf("WHOIS lookup for %s failed: %v\n", domain, err)
+					continue
+				}
+				mu.Lock()
+				results[domain] = record
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, domain := range domains {
+		jobs <- domain
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+// whoisLookup finds the registry server for the TLD of domain through
+// IANA, caching it in servers, and queries it. Thin registries that only
+// name the registrar's server are followed one hop further.
+func whoisLookup(domain string, limiter *whoisLimiter, servers *sync.Map) (WhoisRecord, error) {
+	tld := domain[strings.LastIndexByte(domain, '.')+1:]
+	server, ok := servers.Load(tld)
+	if !ok {
+		resp, err := whoisQuery(whoisIANA, tld)
+		if err != nil {
+			return WhoisRecord{}, err
+		}
+		refer := whoisValue(resp, "refer", "whois")
+		if refer == "" {
+			return WhoisRecord{}, fmt.Errorf("IANA names no WHOIS server for .%s", tld)
+		}
+		server, _ = servers.LoadOrStore(tld, refer)
+	}
+
+	limiter.wait(tld)
+	resp, err := whoisQuery(server.(string), domain)
+	if err != nil {
+		return WhoisRecord{}, err
+	}
+	record := parseWhois(domain, resp)
+	record.Server = server.(string)
+	if referral := whoisValue(resp, "registrar whois server"); referral != "" && !strings.EqualFold(referral, record.Server) {
+		if detail, err := whoisQuery(referral, domain); err == nil {
+			registrar := parseWhois(domain, detail)
+			registrar.Server = referral
+			mergeWhois(&registrar, record)
+			record = registrar
+		}
+	}
+	return record, nil
+}
+
+// whoisQuery sends query to the WHOIS server on port 43 and returns the
+// whole response.
+func whoisQuery(server, query string) (string, error) {
+	server = strings.TrimPrefix(strings.TrimPrefix(server, "whois://"), "rwhois://")
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "43")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	conn, err := newDialer().DialContext(ctx, "tcp", server)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(15 * time.Second))
+
+	if _, err := fmt.Fprintf(conn, "%s\r\n", query); err != nil {
+		return "", err
+	}
+	resp, err := io.ReadAll(io.LimitReader(conn, 1<<20))
+	if err != nil && len(resp) == 0 {
+		return "", fmt.Errorf("failed to read WHOIS response from %s: %w", server, err)
+	}
+	return string(resp), nil
+}
+
+// whoisValue returns the value of the first "key: value" line whose key
+// is one of keys, compared case-insensitively.
+func whoisValue(resp string, keys ...string) string {
+	scanner := bufio.NewScanner(strings.NewReader(resp))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		for _, want := range keys {
+			if key == want {
+				if value = strings.TrimSpace(value); value != "" {
+					return value
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// parseWhois pulls the registrar, registrant, dates and nameservers out
+// of a WHOIS response. The first value for each field wins.
+func parseWhois(domain, resp string) WhoisRecord {
+	record := WhoisRecord{Domain: domain, Timestamp: time.Now().UTC()}
+	scanner := bufio.NewScanner(strings.NewReader(resp))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		value = strings.TrimSpace(value)
+		if !ok || value == "" {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		if key == "name server" || key == "nserver" {
+			record.NameServers = append(record.NameServers, strings.ToLower(strings.Fields(value)[0]))
+			continue
+		}
+		if field, ok := whoisFields[key]; ok {
+			if p := field(&record); *p == "" {
+				*p = value
+			}
+		}
+	}
+	return record
+}
+
+// mergeWhois fills the empty fields of dst from src.
+func mergeWhois(dst *WhoisRecord, src WhoisRecord) {
+	for _, field := range []struct{ dst, src *string }{
+		{&dst.Registrar, &src.Registrar},
+		{&dst.RegistrantOrg, &src.RegistrantOrg},
+		{&dst.RegistrantEmail, &src.RegistrantEmail},
+		{&dst.Created, &src.Created},
+		{&dst.Expires, &src.Expires},
+	} {
+		if *field.dst == "" {
+			*field.dst = *field.src
+		}
+	}
+	if len(dst.NameServers) == 0 {
+		dst.NameServers = src.NameServers
+	}
+}
+
+// redactedRegistrant reports whether value is a privacy placeholder that
+// says nothing about who registered the domain.
+func redactedRegistrant(value string) bool {
+	value = strings.ToLower(value)
+	for _, marker := range []string{"redacted", "privacy", "not disclosed", "data protected", "withheld", "proxy"} {
+		if strings.Contains(value, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// groupRegistrants returns the domains that share a registrant email or
+// organization, keyed by that value. Redacted placeholders never group.
+func groupRegistrants(records map[string]WhoisRecord) map[string][]string {
+	groups := make(map[string][]string)
+	for domain, record := range records {
+		keys := make(map[string]bool)
+		for _, value := range []string{record.RegistrantEmail, record.RegistrantOrg} {
+			if value != "" && !redactedRegistrant(value) {
+				keys[strings.ToLower(value)] = true
+			}
+		}
+		for key := range keys {
+			groups[key] = append(groups[key], domain)
+		}
+	}
+	for key, domains := range groups {
+		if len(domains) < 2 {
+			delete(groups, key)
+			continue
+		}
+		sort.Strings(domains)
+	}
+	return groups
+}
+
+// checkWHOIS looks up the apex domains in parallel, prints each
+// registration and the groups of domains that likely share a registrant.
+// In -json mode every record and every group is also written as a JSON
+// line.
+func checkWHOIS(domains []string, output *os.File) map[string]WhoisRecord {
+	records := parallelWHOIS(domains)
+	groups := groupRegistrants(records)
+	for _, domain := range domains {
+		record, ok := records[domain]
+		if !ok {
+			continue
+		}
+		for _, members := range groups {
+			if !slices.Contains(members, domain) {
+				continue
+			}
+			for _, member := range members {
+				if member != domain && !slices.Contains(record.SameRegistrant, member) {
+					record.SameRegistrant = append(record.SameRegistrant, member)
+				}
+			}
+		}
+		records[domain] = record
+		fmt.Fprintf(status, " - [WHOIS] %s registrar %q registrant %q %s created %s expires %s\n",
+			domain, record.Registrar, record.RegistrantOrg, record.RegistrantEmail, record.Created, record.Expires)
+		if jsonOutput {
+			writeJSONLine(record, domain, output)
+		}
+	}
+
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(status, " - [SAME-REGISTRANT] %s: %s\n", key, strings.Join(groups[key], ", "))
+		if jsonOutput {
+			writeJSONLine(RegistrantGroup{Registrant: key, Domains: groups[key]}, key, output)
+		}
+	}
+	return records
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// DiscoveryRecord is something found during enumeration. DNS records carry
+// their RDATA already rendered in RFC 1035 presentation format, probed
+// hosts carry what was learned about them over HTTP.
+type DiscoveryRecord struct {
+	Name  string
+	Type  dnsmessage.Type
+	Class dnsmessage.Class
+	TTL   uint32
+	Data  string
+
+	RedirectChain []string
+	Service       string
+	TLSVersion    string
+}
+
+func newDiscoveryRecord(rr dnsmessage.Resource) DiscoveryRecord {
+	return DiscoveryRecord{
+		Name:  strings.TrimSuffix(rr.Header.Name.String(), "."),
+		Type:  rr.Header.Type,
+		Class: rr.Header.Class,
+		TTL:   rr.Header.TTL,
+		Data:  rdataString(rr.Body),
+	}
+}
+
+// recordNames returns the owner names of the address and alias records,
+// which are the ones reported as subdomains.
+func recordNames(records []DiscoveryRecord) []string {
+	var names []string
+	for _, record := range records {
+		if record.Type == dnsmessage.TypeA || record.Type == dnsmessage.TypeCNAME {
+			names = append(names, record.Name)
+		}
+	}
+	return names
+}
+
+// recordFindings turns the address and alias records into one finding per
+// owner name, gathering the addresses of its A records.
+func recordFindings(records []DiscoveryRecord, source string) []Finding {
+	var findings []Finding
+	index := make(map[string]int)
+	for _, record := range records {
+		if record.Type != dnsmessage.TypeA && record.Type != dnsmessage.TypeCNAME {
+			continue
+		}
+		i, ok := index[record.Name]
+		if !ok {
+			i = len(findings)
+			index[record.Name] = i
+			findings = append(findings, Finding{Name: record.Name, Source: source, RecordType: typeName(record.Type)})
+		}
+		if record.Type == dnsmessage.TypeA {
+			findings[i].Addrs = append(findings[i].Addrs, record.Data)
+		}
+	}
+	return findings
+}
+
+func rdataString(body dnsmessage.ResourceBody) string {
+	switch b := body.(type) {
+	case *dnsmessage.AResource:
+		return net.IP(b.A[:]).String()
+	case *dnsmessage.AAAAResource:
+		return net.IP(b.AAAA[:]).String()
+	case *dnsmessage.NSResource:
+		return b.NS.String()
+	case *dnsmessage.CNAMEResource:
+		return b.CNAME.String()
+	case *dnsmessage.PTRResource:
+		return b.PTR.String()
+	case *dnsmessage.MXResource:
+		return fmt.Sprintf("%d %s", b.Pref, b.MX)
+	case *dnsmessage.SRVResource:
+		return fmt.Sprintf("%d %d %d %s", b.Priority, b.Weight, b.Port, b.Target)
+	case *dnsmessage.SOAResource:
+		return fmt.Sprintf("%s %s %d %d %d %d %d", b.NS, b.MBox, b.Serial, b.Refresh, b.Retry, b.Expire, b.MinTTL)
+	case *dnsmessage.TXTResource:
+		quoted := make([]string, len(b.TXT))
+		for i, txt := range b.TXT {
+			quoted[i] = quoteTXT(txt)
+		}
+		return strings.Join(quoted, " ")
+	case *dnsmessage.UnknownResource:
+		return parseRawRDATA(b.Type, b.Data)
+	}
+	return ""
+}
+
+// genericRDATA renders data in the RFC 3597 unknown-type format.
+func genericRDATA(data []byte) string {
+	if len(data) == 0 {
+		return `\# 0`
+	}
+	return fmt.Sprintf(`\# %d %s`, len(data), hex.EncodeToString(data))
+}
+
+func quoteTXT(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < ' ' || c > '~':
+			fmt.Fprintf(&b, "\\%03d", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+func className(c dnsmessage.Class) string {
+	switch c {
+	case dnsmessage.ClassINET:
+		return "IN"
+	case dnsmessage.ClassCSNET:
+		return "CS"
+	case dnsmessage.ClassCHAOS:
+		return "CH"
+	case dnsmessage.ClassHESIOD:
+		return "HS"
+	}
+	return "CLASS" + strconv.Itoa(int(c))
+}
+
+// writeBindZone writes records as a zone file for origin. The SOA record
+// comes first and sets $TTL, duplicate records (AXFR repeats the SOA at
+// the end, and several nameservers return the same zone) are dropped.
+func writeBindZone(records []DiscoveryRecord, origin string, w io.Writer) error {
+	origin = strings.TrimSuffix(origin, ".")
+	var soa *DiscoveryRecord
+	for i := range records {
+		if records[i].Type == dnsmessage.TypeSOA && strings.EqualFold(records[i].Name, origin) {
+			soa = &records[i]
+			break
+		}
+	}
+	if soa == nil {
+		return fmt.Errorf("no SOA record for %s", origin)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 1, '\t', 0)
+	fmt.Fprintf(tw, "$ORIGIN %s.\n", origin)
+	fmt.Fprintf(tw, "$TTL %d\n", soa.TTL)
+	line := zoneLine(*soa, origin)
+	fmt.Fprint(tw, line)
+
+	seen := map[string]bool{line: true}
+	for _, record := range records {
+		if record.Type == dnsmessage.TypeOPT {
+			continue
+		}
+		line := zoneLine(record, origin)
+		if seen[line] {
+			continue
+		}
+		seen[line] = true
+		fmt.Fprint(tw, line)
+	}
+	return tw.Flush()
+}
+
+func zoneLine(record DiscoveryRecord, origin string) string {
+	owner := record.Name + "."
+	if strings.EqualFold(record.Name, origin) {
+		owner = "@"
+	} else if strings.HasSuffix(strings.ToLower(record.Name), "."+strings.ToLower(origin)) {
+		owner = record.Name[:len(record.Name)-len(origin)-1]
+	}
+	return fmt.Sprintf("%s\t%d\t%s\t%s\t%s\n", owner, record.TTL, className(record.Class), typeName(record.Type), record.Data)
+}
+
+// zoneMu serialises zone writes from concurrently enumerated domains.
+var zoneMu sync.Mutex
+
+// writeZoneOutput appends the zone for origin to w when zone output is on.
+func writeZoneOutput(records []DiscoveryRecord, origin string, w *os.File) {
+	if w == nil || len(records) == 0 {
+		return
+	}
+	zoneMu.Lock()
+	defer zoneMu.Unlock()
+	if err := writeBindZone(records, origin, w); err != nil {
+		log.Printf("Failed to write zone for %s: %v\n", origin, err)
+		return
+	}
+	fmt.Fprintln(w)
+}
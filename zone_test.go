@@ -0,0 +1,51 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestWriteBindZone(t *testing.T) {
+	soa := DiscoveryRecord{Name: "example.com", Type: dnsmessage.TypeSOA, Class: dnsmessage.ClassINET, TTL: 3600, Data: "ns1.example.com. hostmaster.example.com. 2024010101 7200 900 1209600 300"}
+	records := []DiscoveryRecord{
+		soa,
+		{Name: "example.com", Type: dnsmessage.TypeNS, Class: dnsmessage.ClassINET, TTL: 3600, Data: "ns1.example.com."},
+		{Name: "www.example.com", Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 300, Data: "192.0.2.10"},
+		{Name: "www.example.com", Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 300, Data: "192.0.2.10"},
+		{Name: "Dev.Example.com", Type: dnsmessage.TypeCNAME, Class: dnsmessage.ClassINET, TTL: 60, Data: "www.example.com."},
+		{Name: "txt.example.com", Type: dnsmessage.TypeTXT, Class: dnsmessage.ClassCHAOS, TTL: 0, Data: `"v=spf1 -all"`},
+		{Name: "glue.example.net", Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 300, Data: "198.51.100.1"},
+		{Name: "", Type: dnsmessage.TypeOPT, Class: 4096, Data: `\# 0`},
+		soa,
+	}
+	want := []string{
+		"$ORIGIN example.com.",
+		"$TTL 3600",
+		"@ 3600 IN SOA ns1.example.com. hostmaster.example.com. 2024010101 7200 900 1209600 300",
+		"@ 3600 IN NS ns1.example.com.",
+		"www 300 IN A 192.0.2.10",
+		"Dev 60 IN CNAME www.example.com.",
+		`txt 0 CH TXT "v=spf1 -all"`,
+		"glue.example.net. 300 IN A 198.51.100.1",
+	}
+
+	var b strings.Builder
+	if err := writeBindZone(records, "example.com.", &b); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("writeBindZone wrote %d lines, want %d:\n%s", len(lines), len(want), b.String())
+	}
+	for i, line := range lines {
+		if got := strings.Join(strings.Fields(line), " "); got != want[i] {
+			t.Errorf("line %d = %q, want %q", i+1, got, want[i])
+		}
+	}
+
+	if err := writeBindZone(records[1:3], "example.com", &b); err == nil {
+		t.Error("writeBindZone without an SOA record succeeded, want an error")
+	}
+}